@@ -0,0 +1,35 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the agent in its own process group so that it can be
+// signalled together with every child it spawns.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcess asks the agent's process group to shut down gracefully.
+func terminateProcess(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// killProcess forcefully stops the agent's whole process group.
+func killProcess(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// shellCommand runs a command line through the POSIX shell.
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command)
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows, which has no process groups in the
+// POSIX sense.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcess stops the agent. Windows cannot deliver SIGTERM, so this
+// is the same as killProcess.
+func terminateProcess(cmd *exec.Cmd) {
+	killProcess(cmd)
+}
+
+// killProcess forcefully stops the agent.
+func killProcess(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	_ = cmd.Process.Kill()
+}
+
+// shellCommand runs a command line through cmd.exe.
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/charmbracelet/bubbles/filepicker"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -32,25 +43,479 @@ var (
 	selectedStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("170")).
 			Bold(true)
+
+	outputStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("241"))
+)
+
+// defaultAgent is the agent offered first in the TUI and used by the headless
+// runner when neither -agent nor -profile is given. A profile of the same name
+// in agents.json replaces it.
+var defaultAgent = AgentProfile{
+	Name:   "claude",
+	Binary: "claude",
+	Args:   []string{"--dangerously-skip-permissions"},
+	Prompt: PromptArgv,
+}
+
+// agentProfilesFile holds the team's shared agent profiles.
+const agentProfilesFile = "agents.json"
+
+// PromptMode is how the prompt is handed to an agent.
+type PromptMode string
+
+const (
+	PromptArgv  PromptMode = "argv"  // Appended as the last argument
+	PromptStdin PromptMode = "stdin" // Piped to standard input
+	PromptFile  PromptMode = "file"  // Written to a temporary file
 )
 
+var promptModes = []PromptMode{PromptArgv, PromptStdin, PromptFile}
+
+// promptFilePlaceholder is replaced by the prompt file's path in the
+// arguments of an agent using PromptFile. The path is also passed in the
+// promptFileEnv environment variable.
+const (
+	promptFilePlaceholder = "{prompt_file}"
+	promptFileEnv         = "PROMPT_FILE"
+)
+
+func parsePromptMode(s string) (PromptMode, error) {
+	for _, mode := range promptModes {
+		if string(mode) == s {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown prompt mode %q (want argv, stdin or file)", s)
+}
+
+// next returns the mode that follows p when cycling through them in the TUI.
+func (p PromptMode) next() PromptMode {
+	for i, mode := range promptModes {
+		if mode == p {
+			return promptModes[(i+1)%len(promptModes)]
+		}
+	}
+	return promptModes[0]
+}
+
+// AgentProfile describes how to launch a coding agent.
+type AgentProfile struct {
+	Name   string            `json:"name"`
+	Binary string            `json:"binary"`
+	Args   []string          `json:"args,omitempty"`
+	Env    map[string]string `json:"env,omitempty"`
+	Dir    string            `json:"dir,omitempty"`    // Working directory, relative to the checkout
+	Prompt PromptMode        `json:"prompt,omitempty"` // Defaults to argv
+
+	// Output and exit codes that mean the agent was rate limited rather than
+	// failing at the ticket. Patterns are regular expressions and replace
+	// defaultRateLimitPatterns.
+	RateLimitPatterns  []string `json:"rate_limit_patterns,omitempty"`
+	RateLimitExitCodes []int    `json:"rate_limit_exit_codes,omitempty"`
+}
+
+// String returns the profile's command line, quoting arguments that would
+// otherwise be split.
+func (p AgentProfile) String() string {
+	parts := []string{}
+	for _, part := range append([]string{p.Binary}, p.Args...) {
+		if part == "" || strings.ContainsAny(part, " \t\n'\"\\") {
+			part = strconv.Quote(part)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// validate checks a profile loaded from the config file.
+func (p AgentProfile) validate() error {
+	if p.Name == "" {
+		return errors.New("profile without a name")
+	}
+	if p.Binary == "" {
+		return fmt.Errorf("profile %q has no binary", p.Name)
+	}
+	if _, err := parsePromptMode(string(p.Prompt)); err != nil {
+		return fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+	for _, pattern := range p.RateLimitPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("profile %q: rate limit pattern: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// rateLimited reports whether an agent that exited with exitCode and printed
+// output ran into a rate limit. The output is only checked after a non-zero
+// exit, as a clean run may well have worked on rate limiting itself.
+func (p AgentProfile) rateLimited(output string, exitCode int) bool {
+	for _, code := range p.RateLimitExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	if exitCode == 0 {
+		return false
+	}
+	patterns := p.RateLimitPatterns
+	if len(patterns) == 0 {
+		patterns = defaultRateLimitPatterns
+	}
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAgentProfiles returns the built-in profile followed by the profiles in
+// the config file. A missing config file is not an error.
+func loadAgentProfiles(path string) ([]AgentProfile, error) {
+	profiles := []AgentProfile{defaultAgent}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return profiles, err
+	}
+
+	var config struct {
+		Profiles []AgentProfile `json:"profiles"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return profiles, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for _, profile := range config.Profiles {
+		if profile.Prompt == "" {
+			profile.Prompt = PromptArgv
+		}
+		if err := profile.validate(); err != nil {
+			return []AgentProfile{defaultAgent}, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if seen[profile.Name] {
+			return []AgentProfile{defaultAgent}, fmt.Errorf("reading %s: duplicate profile %q", path, profile.Name)
+		}
+		seen[profile.Name] = true
+		if profile.Name == defaultAgent.Name {
+			profiles[0] = profile
+		} else {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+// findProfile returns the profile with the given name.
+func findProfile(profiles []AgentProfile, name string) (AgentProfile, bool) {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return AgentProfile{}, false
+}
+
+// customProfile turns a command line typed by the user into a profile.
+func customProfile(command string) (AgentProfile, error) {
+	parts, err := splitCommand(command)
+	if err != nil {
+		return AgentProfile{}, err
+	}
+	if len(parts) == 0 {
+		return AgentProfile{}, errors.New("empty agent command")
+	}
+	return AgentProfile{Name: "custom", Binary: parts[0], Args: parts[1:], Prompt: PromptArgv}, nil
+}
+
+// splitCommand splits a command line into words like a POSIX shell does,
+// honoring single quotes, double quotes and backslash escapes.
+func splitCommand(command string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && quote == 0,
+			r == '\\' && quote == '"':
+			escaped = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+		if escaped {
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
 type tickMsg struct {
-	output string
+	ticket int
 	err    error
 }
 
-type processCompleteMsg struct{}
+// processCompleteMsg finalizes a ticket. err is nil when the agent succeeded.
+type processCompleteMsg struct {
+	ticket int
+	status TicketStatus  // Outcome of the attempt
+	err    error         // Why the attempt did not succeed
+	wait   time.Duration // Only set for rate limits
+}
 
-type waitingDoneMsg struct{}
+type waitingDoneMsg struct {
+	until time.Time
+}
 
-type checkKillFileMsg struct{}
+type checkKillFileMsg struct {
+	ticket int
+	cmd    *exec.Cmd
+}
 
 type killFileFoundMsg struct {
+	ticket  int
 	content string
 }
 
+// CompletionReport is what an agent writes to its kill file: either a JSON
+// object or just the status word.
+type CompletionReport struct {
+	Status       string   `json:"status"` // success, failure, partial or blocked
+	Summary      string   `json:"summary,omitempty"`
+	FilesChanged []string `json:"files_changed,omitempty"`
+	FollowUps    []string `json:"follow_ups,omitempty"`
+	NewTickets   []struct {
+		Description string `json:"description"`
+		Body        string `json:"body,omitempty"`
+	} `json:"new_tickets,omitempty"` // Proposed follow-up tickets
+}
+
+var reportStatuses = []string{"success", "failure", "partial", "blocked"}
+
+// parseCompletionReport reads the content of a kill file. Plain text must
+// start with one of the status words, anything after it is the summary.
+func parseCompletionReport(content string) (CompletionReport, error) {
+	content = strings.TrimSpace(content)
+	report := CompletionReport{}
+	if strings.HasPrefix(content, "{") {
+		if err := json.Unmarshal([]byte(content), &report); err != nil {
+			return report, fmt.Errorf("invalid completion report: %w", err)
+		}
+		report.Status = strings.ToLower(strings.TrimSpace(report.Status))
+	} else {
+		fields := strings.Fields(content)
+		if len(fields) > 0 {
+			report.Status = strings.ToLower(strings.Trim(fields[0], ".,:;!"))
+			report.Summary = strings.TrimSpace(strings.TrimPrefix(content, fields[0]))
+		}
+	}
+
+	for _, status := range reportStatuses {
+		if report.Status == status {
+			return report, nil
+		}
+	}
+	return report, fmt.Errorf("invalid completion report: unknown status %q", report.Status)
+}
+
+// reportComplete tells whether a kill file is fully written. A JSON report is
+// complete once it parses.
+func reportComplete(content string) bool {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "{") {
+		return json.Valid([]byte(content))
+	}
+	return content != ""
+}
+
+// err returns the failure to record for the report, or nil on success.
+func (r CompletionReport) err() error {
+	reason := "agent reported failure"
+	switch r.Status {
+	case "success":
+		return nil
+	case "partial":
+		reason = "agent finished only partially"
+	case "blocked":
+		reason = "agent is blocked"
+	}
+	if r.Summary != "" {
+		reason += ": " + r.Summary
+	}
+	return errors.New(reason)
+}
+
 type processStartedMsg struct {
-	cmd *exec.Cmd
+	ticket       int
+	cmd          *exec.Cmd
+	logPath      string
+	promptFile   string
+	branch       string
+	worktreePath string
+}
+
+// processExitedMsg reports that an agent process has terminated and been
+// reaped. cmd identifies the process so exits of agents that were already
+// handled (e.g. killed after writing the kill file) can be ignored.
+type processExitedMsg struct {
+	ticket   int
+	cmd      *exec.Cmd
+	exitCode int
+	err      error // Set when the process could not be waited on
+}
+
+// ticketTimeoutMsg fires when an agent has exceeded its ticket's timeout.
+type ticketTimeoutMsg struct {
+	ticket  int
+	cmd     *exec.Cmd
+	timeout time.Duration
+}
+
+// killEscalationMsg fires when a terminated agent is still running after the
+// grace period and has to be killed.
+type killEscalationMsg struct {
+	ticket int
+	cmd    *exec.Cmd
+}
+
+// ticketVerifiedMsg reports the result of a ticket's verification commands.
+// cmd identifies the attempt so results of a retried attempt can be ignored.
+type ticketVerifiedMsg struct {
+	ticket  int
+	cmd     *exec.Cmd
+	logPath string
+	err     error
+}
+
+// verifyCommandDoneMsg reports that one of a ticket's verification commands
+// has exited. cmd is the verification command, step its position in the
+// ticket's list of commands.
+type verifyCommandDoneMsg struct {
+	ticket  int
+	cmd     *exec.Cmd
+	step    int
+	logPath string
+	err     error
+}
+
+// verifyTimeoutMsg fires when a ticket's verification has exceeded the
+// ticket's timeout. cmd is the agent of the verified attempt.
+type verifyTimeoutMsg struct {
+	ticket  int
+	cmd     *exec.Cmd
+	logPath string
+	timeout time.Duration
+}
+
+// ticketCommittedMsg reports the commit of a successful ticket's changes.
+// An empty sha without an error means there was nothing to commit. cmd
+// identifies the attempt like in ticketVerifiedMsg.
+type ticketCommittedMsg struct {
+	ticket   int
+	cmd      *exec.Cmd
+	sha      string
+	diffStat string
+	err      error
+}
+
+// runningAgent tracks the agent process working on one ticket.
+type runningAgent struct {
+	Cmd         *exec.Cmd // nil until the process has started
+	Terminating bool      // Whether the agent is being shut down after a timeout
+	Committing  bool      // Whether the agent's work is being committed
+	Verifying   bool      // Whether the verification commands are running
+	Verified    bool      // Whether the verification commands passed
+	VerifyCmd   *exec.Cmd // The verification command being run
+	PromptFile  string    // Temporary prompt file, removed when the agent is done
+}
+
+// stop kills the agent and its verification command and removes its prompt
+// file.
+func (a *runningAgent) stop() {
+	killProcess(a.Cmd)
+	killProcess(a.VerifyCmd)
+	a.removePromptFile()
+}
+
+func (a *runningAgent) removePromptFile() {
+	if a.PromptFile != "" {
+		_ = os.Remove(a.PromptFile)
+		a.PromptFile = ""
+	}
+}
+
+// ExitPolicy decides how a ticket is scored when its agent exits cleanly
+// without writing its kill file.
+type ExitPolicy string
+
+const (
+	ExitPolicySuccess ExitPolicy = "success"
+	ExitPolicyFailure ExitPolicy = "failure"
+	ExitPolicyUnknown ExitPolicy = "unknown"
+)
+
+var exitPolicies = []ExitPolicy{ExitPolicyUnknown, ExitPolicySuccess, ExitPolicyFailure}
+
+func parseExitPolicy(s string) (ExitPolicy, error) {
+	for _, policy := range exitPolicies {
+		if string(policy) == s {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown exit policy %q (want success, failure or unknown)", s)
+}
+
+// next returns the policy that follows p when cycling through them in the TUI.
+func (p ExitPolicy) next() ExitPolicy {
+	for i, policy := range exitPolicies {
+		if policy == p {
+			return exitPolicies[(i+1)%len(exitPolicies)]
+		}
+	}
+	return exitPolicies[0]
 }
 
 type fileCheckResult struct {
@@ -60,14 +525,35 @@ type fileCheckResult struct {
 	MissingFiles        []string
 	TicketCount         int
 	ParsedTickets       []Ticket
+	PromptError         error // standard-prompt.md is not a valid template
+	VerifyCommands      []string
+	VerifyError         error     // verify.txt exists but cannot be read
+	PreviousRun         *runState // The project's last saved run, if any
 }
 
-type proceedToAgentSelectionMsg struct{}
+type proceedToTicketSelectionMsg struct{}
 
 type projectsScannedMsg struct {
-	projects []string
+	projects    []string
+	savedRuns   map[string]*runState
+	profiles    []AgentProfile
+	profilesErr error
+}
+
+// runState is persisted to the project folder after every finished ticket so
+// that an interrupted run can be resumed on the next launch.
+type runState struct {
+	Project      string
+	AgentCommand string
+	RunDir       string
+	UpdatedAt    time.Time
+	Tickets      []Ticket
+	Selection    []int // Numbers of the tickets chosen for the run, in run order
 }
 
+// runStateFile is the name of the run state file inside a project folder.
+const runStateFile = ".run-state.json"
+
 type AppState int
 
 const (
@@ -75,6 +561,7 @@ const (
 	StateFileCheck
 	StateFileCheckResults
 	StateFilePicker
+	StateTicketSelection
 	StateAgentSelection
 	StateCustomCommandEntry
 	StateConfirmation
@@ -91,11 +578,17 @@ type Model struct {
 	AvailableProjects    []string
 	SelectedProject      string
 	SelectedProjectIndex int
+	SavedRuns            map[string]*runState // Resumable runs by project
+	ResumeState          *runState            // Run being resumed, if any
 
 	// File paths
 	SpecificationPath   string
 	TicketsPath         string
 	StandardPromptPath  string
+	PromptError         error    // Template error in standard-prompt.md
+	VerifyCommands      []string // From the project's verify.txt
+	VerifyError         error    // verify.txt cannot be read
+	StaleKillFiles      []string // Removed when the run starts
 	MissingFiles        []string
 	CurrentMissingIndex int
 
@@ -104,31 +597,263 @@ type Model struct {
 	TextInput  textinput.Model
 
 	// Agent selection
-	SelectedAgent      int // 0 for claude-code, 1 for other
-	CustomAgentCommand string
+	Profiles      []AgentProfile // Built-in and configured agents
+	SelectedAgent int            // Index into Profiles, len(Profiles) for a custom command
+	Agent         AgentProfile   // Agent the tickets run with
+	AgentError    error          // Invalid config file or custom command
 
 	// Execution state
-	Tickets        []Ticket
-	CurrentTicket  int
-	ProcessRunning bool
-	ProcessError   error
-	CurrentCmd     *exec.Cmd // Track running command
-	DelaySeconds   int       // Delay between agents
-	IsWaiting      bool      // Whether we're in waiting state
-	WaitingUntil   time.Time // When to start next agent
+	Tickets       []Ticket
+	Running       map[int]*runningAgent // Running agents by ticket index
+	Concurrency   int                   // Maximum number of agents running at once
+	ProcessError  error                 // Most recent ticket failure
+	DelaySeconds  int                   // Delay between agents
+	IsWaiting     bool                  // Whether we're in waiting state
+	WaitingUntil  time.Time             // When to start next agent
+	WaitReason    string                // Why the wait is longer than the delay
+	RateLimitHits int                   // Rate limits since the last success
+	RunDir        string                // Per-run directory holding the agent logs
+	ExitPolicy    ExitPolicy            // Outcome of a clean exit without kill file
+	Timeout       time.Duration         // Per-ticket timeout, 0 disables it
+	GracePeriod   time.Duration         // Time between SIGTERM and SIGKILL
+	UseWorktrees  bool                  // Run each ticket in its own git worktree
+	AutoCommit    bool                  // Commit a ticket's changes when it succeeds
+	Retries       int                   // Extra attempts for failed tickets
+	Paused        bool                  // Let running agents finish but start no new ones
+	Aborted       bool                  // The run was stopped before all tickets finished
+
+	// Follow-up tickets proposed by agents
+	ProposedTickets  []Ticket // Waiting for approval, oldest first
+	AutoApprove      bool     // Add proposed tickets without asking
+	WriteBackTickets bool     // Append approved tickets to tickets.md
+
+	// Ticket selection
+	Selected        map[int]bool // Ticket numbers chosen to run
+	SelectionCursor int
+	SelectionAnchor int       // Index where a range selection started, -1 if none
+	OnlyFailed      bool      // Select only tickets that failed in the previous run
+	PreviousRun     *runState // The project's last saved run
+
+	// Agent output
+	Output       viewport.Model // Log of the ticket under the cursor
+	ShowOutput   bool           // Whether the running view shows the output pane
+	FollowOutput bool           // Keep the output scrolled to its newest line
+	ShowDetails  bool           // Whether the ticket details are shown below the list
 
 	// UI state
 	Cursor       int
 	ConfirmReady bool
 }
 
+// TicketStatus is where a ticket stands in the run. The zero value is
+// pending, so newly parsed tickets need no status.
+type TicketStatus string
+
+const (
+	StatusPending      TicketStatus = ""
+	StatusRunning      TicketStatus = "running" // Includes verifying and committing
+	StatusRateLimited  TicketStatus = "rate-limited"
+	StatusSucceeded    TicketStatus = "succeeded"
+	StatusFailed       TicketStatus = "failed" // Reported failure or could not start
+	StatusVerifyFailed TicketStatus = "verification-failed"
+	StatusTimedOut     TicketStatus = "timed-out"
+	StatusCrashed      TicketStatus = "crashed" // Exited with an error code
+	StatusUnknown      TicketStatus = "unknown" // Exited cleanly without a result
+	StatusSkipped      TicketStatus = "skipped"
+	StatusCancelled    TicketStatus = "cancelled" // Interrupted by aborting the run
+	StatusBlocked      TicketStatus = "blocked"   // A dependency can never succeed
+)
+
+// ticketTransitions lists the statuses each status may change to. Every
+// finished ticket can be queued again by retrying it.
+var ticketTransitions = map[TicketStatus][]TicketStatus{
+	StatusPending: {StatusRunning, StatusSkipped, StatusBlocked},
+	StatusRunning: {StatusSucceeded, StatusFailed, StatusVerifyFailed, StatusTimedOut, StatusCrashed,
+		StatusUnknown, StatusRateLimited, StatusSkipped, StatusCancelled, StatusPending},
+	StatusRateLimited:  {StatusRunning, StatusSkipped, StatusBlocked, StatusCancelled, StatusPending},
+	StatusCancelled:    {StatusRunning, StatusSkipped, StatusBlocked, StatusPending},
+	StatusSucceeded:    {StatusPending},
+	StatusFailed:       {StatusPending},
+	StatusVerifyFailed: {StatusPending},
+	StatusTimedOut:     {StatusPending},
+	StatusCrashed:      {StatusPending},
+	StatusUnknown:      {StatusPending},
+	StatusSkipped:      {StatusPending},
+	StatusBlocked:      {StatusPending},
+}
+
+// canBecome reports whether a ticket may change from s to next.
+func (s TicketStatus) canBecome(next TicketStatus) bool {
+	for _, allowed := range ticketTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// finished reports whether the status is a final result.
+func (s TicketStatus) finished() bool {
+	switch s {
+	case StatusPending, StatusRunning, StatusRateLimited, StatusCancelled:
+		return false
+	}
+	return true
+}
+
+// failed reports whether the ticket did not get its work done, which blocks
+// the tickets depending on it.
+func (s TicketStatus) failed() bool {
+	switch s {
+	case StatusFailed, StatusVerifyFailed, StatusTimedOut, StatusCrashed, StatusBlocked:
+		return true
+	}
+	return false
+}
+
+// icon is shown in front of the ticket in the running and completed views.
+func (s TicketStatus) icon() string {
+	switch s {
+	case StatusRunning:
+		return "🔄"
+	case StatusRateLimited:
+		return "🚦"
+	case StatusSucceeded:
+		return "✅"
+	case StatusFailed:
+		return "❌"
+	case StatusVerifyFailed:
+		return "🧪"
+	case StatusTimedOut:
+		return "⌛"
+	case StatusCrashed:
+		return "💥"
+	case StatusUnknown:
+		return "❔"
+	case StatusSkipped:
+		return "⏭️ "
+	case StatusCancelled:
+		return "⏹️ "
+	case StatusBlocked:
+		return "🚫"
+	}
+	return "⏳"
+}
+
+// label names the status in the summaries.
+func (s TicketStatus) label() string {
+	switch s {
+	case StatusPending:
+		return "Not run"
+	case StatusRunning:
+		return "Running"
+	case StatusRateLimited:
+		return "Rate limited"
+	case StatusSucceeded:
+		return "Successful"
+	case StatusVerifyFailed:
+		return "Verification failed"
+	case StatusTimedOut:
+		return "Timed out"
+	}
+	return strings.ToUpper(string(s[:1])) + string(s[1:])
+}
+
+// summaryStatuses is the order of the per-status counts in the summaries.
+var summaryStatuses = []TicketStatus{
+	StatusSucceeded, StatusFailed, StatusVerifyFailed, StatusTimedOut, StatusCrashed, StatusBlocked,
+	StatusUnknown, StatusSkipped, StatusCancelled, StatusRateLimited, StatusPending,
+}
+
+// statusCounts counts the tickets per status.
+func statusCounts(tickets []Ticket) map[TicketStatus]int {
+	counts := map[TicketStatus]int{}
+	for _, ticket := range tickets {
+		counts[ticket.Status]++
+	}
+	return counts
+}
+
 type Ticket struct {
-	Number      int
-	Description string
-	Completed   bool
-	Failed      bool
-	StartTime   time.Time
-	EndTime     time.Time
+	Number        int
+	Description   string
+	Status        TicketStatus
+	StartTime     time.Time
+	EndTime       time.Time
+	LogPath       string // Combined stdout/stderr of the agent
+	ExitCode      int
+	FailureReason string
+	Timeout       time.Duration // Overrides Model.Timeout when set
+	Body          string        // Markdown below the heading, up to the next ticket
+	Dependencies  []int         // Numbers of tickets that must succeed first
+	Branch        string        // Git branch of the ticket's worktree
+	WorktreePath  string        // Directory the agent ran in, if isolated
+	CommitSHA     string        // Commit holding the ticket's changes
+	DiffStat      string        // Output of git diff --stat for the commit
+	NoOp          bool          // Succeeded without changing any files
+	Agent         string        // Agent profile overriding the run's agent
+	Dir           string        // Working subdirectory overriding the profile's
+	Labels        []string
+	Retries       *int // Extra attempts after a failure, overriding Model.Retries when set
+	Attempts      int  // Number of times the agent was started
+	Report        *CompletionReport
+	ProposedBy    int       // Number of the ticket whose agent proposed this one
+	History       []Attempt // Every finished attempt, oldest first
+	RateLimits    int       // Attempts that ran into a rate limit
+	Uncounted     int       // Attempts before a manual retry or interrupted by the user
+	Verify        []string  // Verification commands in addition to the project's
+	VerifyLogPath string    // Output of the verification commands
+}
+
+// Attempt records one run of a ticket's agent.
+type Attempt struct {
+	Number        int
+	StartTime     time.Time
+	EndTime       time.Time
+	Status        TicketStatus
+	FailureReason string
+	ExitCode      int
+	LogPath       string
+	VerifyLogPath string
+}
+
+// String summarizes the attempt for the completed view.
+func (a Attempt) String() string {
+	s := fmt.Sprintf("Attempt %d: %s after %s", a.Number, strings.ToLower(a.Status.label()), formatDuration(a.EndTime.Sub(a.StartTime)))
+	if a.FailureReason != "" {
+		s += fmt.Sprintf(" (%s)", a.FailureReason)
+	}
+	if a.LogPath != "" {
+		s += " - " + a.LogPath
+	}
+	return s
+}
+
+// countedAttempts returns the attempts that count against the ticket's
+// retries. Attempts that ran into a rate limit are not the ticket's fault,
+// and a manual retry starts the count over.
+func (t Ticket) countedAttempts() int {
+	return t.Attempts - t.RateLimits - t.Uncounted
+}
+
+// finished reports whether the ticket has a final result.
+func (t Ticket) finished() bool {
+	return t.Status.finished()
+}
+
+// result names the ticket's final result, or returns "" while it is pending.
+func (t Ticket) result() string {
+	switch {
+	case t.Status == StatusSkipped:
+		return "skipped"
+	case t.Status.failed():
+		return "failure"
+	case t.Status == StatusUnknown:
+		return "unknown"
+	case t.Status == StatusSucceeded:
+		return "success"
+	}
+	return ""
 }
 
 func initialModel() Model {
@@ -136,16 +861,25 @@ func initialModel() Model {
 	ti.Placeholder = "Enter custom agent command..."
 	ti.CharLimit = 200
 
-	return Model{
+	m := Model{
 		State:                StateProjectSelection,
 		MissingFiles:         []string{},
 		TextInput:            ti,
+		Profiles:             []AgentProfile{defaultAgent},
 		SelectedAgent:        0,
 		Tickets:              []Ticket{},
 		DelaySeconds:         2, // Default 2 second delay between agents
+		ExitPolicy:           ExitPolicyUnknown,
+		Concurrency:          1,
+		GracePeriod:          10 * time.Second,
 		SelectedProjectIndex: 0,
 		AvailableProjects:    []string{},
+		Output:               viewport.New(0, 0),
+		ShowOutput:           true,
+		FollowOutput:         true,
+		ShowDetails:          true,
 	}
+	return m.resizeOutput()
 }
 
 func (m Model) Init() tea.Cmd {
@@ -154,107 +888,569 @@ func (m Model) Init() tea.Cmd {
 
 func scanProjects() tea.Msg {
 	projects := []string{}
+	savedRuns := map[string]*runState{}
+
+	profiles, profilesErr := loadAgentProfiles(agentProfilesFile)
 
 	files, err := os.ReadDir("input")
 	if err != nil {
-		return projectsScannedMsg{projects: projects}
+		return projectsScannedMsg{projects: projects, savedRuns: savedRuns, profiles: profiles, profilesErr: profilesErr}
 	}
 
 	for _, file := range files {
 		if file.IsDir() {
 			projects = append(projects, file.Name())
+			if state, err := loadRunState(file.Name()); err == nil && state.resumable() {
+				savedRuns[file.Name()] = state
+			}
 		}
 	}
 
-	return projectsScannedMsg{projects: projects}
+	return projectsScannedMsg{projects: projects, savedRuns: savedRuns, profiles: profiles, profilesErr: profilesErr}
 }
 
-func checkFilesCmd(project string) tea.Cmd {
-	return func() tea.Msg {
-		return checkFiles(project)
-	}
+func runStatePath(project string) string {
+	return filepath.Join("input", project, runStateFile)
 }
 
-func checkFiles(project string) fileCheckResult {
-	result := fileCheckResult{
-		MissingFiles:  []string{},
-		ParsedTickets: []Ticket{},
+func loadRunState(project string) (*runState, error) {
+	content, err := os.ReadFile(runStatePath(project))
+	if err != nil {
+		return nil, err
+	}
+	state := &runState{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", runStatePath(project), err)
 	}
 
-	specPath := fmt.Sprintf("input/%s/specification.md", project)
-	ticketsPath := fmt.Sprintf("input/%s/tickets.md", project)
-	promptPath := fmt.Sprintf("input/%s/standard-prompt.md", project)
+	return state, nil
+}
 
-	if _, err := os.Stat(specPath); os.IsNotExist(err) {
-		result.MissingFiles = append(result.MissingFiles, "specification.md")
-	} else {
-		result.SpecificationFound = true
+// saveRunState writes the current progress to the project folder. It writes
+// to a temporary file first so a crash never leaves a truncated state behind.
+func (m Model) saveRunState() error {
+	state := runState{
+		Project:      m.SelectedProject,
+		AgentCommand: m.Agent.String(),
+		RunDir:       m.RunDir,
+		UpdatedAt:    time.Now(),
+		Tickets:      m.Tickets,
+	}
+	for _, ticket := range m.Tickets {
+		state.Selection = append(state.Selection, ticket.Number)
+	}
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := runStatePath(m.SelectedProject)
+	if err := os.WriteFile(path+".tmp", content, 0o644); err != nil {
+		return err
 	}
+	return os.Rename(path+".tmp", path)
+}
 
-	if _, err := os.Stat(ticketsPath); os.IsNotExist(err) {
-		result.MissingFiles = append(result.MissingFiles, "tickets.md")
-	} else {
-		result.TicketsFound = true
-		// Parse tickets to get count
-		if tickets, err := parseTickets(ticketsPath); err == nil {
-			result.ParsedTickets = tickets
-			result.TicketCount = len(tickets)
+func (s *runState) finishedCount() int {
+	finished := 0
+	for _, ticket := range s.Tickets {
+		if ticket.finished() {
+			finished++
 		}
 	}
+	return finished
+}
 
-	if _, err := os.Stat(promptPath); os.IsNotExist(err) {
-		result.MissingFiles = append(result.MissingFiles, "standard-prompt.md")
-	} else {
-		result.StandardPromptFound = true
+// resumable reports whether the saved run made progress but did not finish.
+func (s *runState) resumable() bool {
+	finished := s.finishedCount()
+	return finished > 0 && finished < len(s.Tickets)
+}
+
+// mergeRunState copies the results of a saved run onto freshly parsed
+// tickets, matching them by ticket number. Only the tickets chosen for the
+// saved run are kept, in its order. Unfinished tickets start again but keep
+// their earlier attempts.
+func mergeRunState(tickets []Ticket, state *runState) []Ticket {
+	parsed := map[int]bool{}
+	for _, ticket := range tickets {
+		parsed[ticket.Number] = true
+	}
+	// Follow-up tickets that were not written to tickets.md are gone
+	numbers := []int{}
+	for _, number := range state.Selection {
+		if parsed[number] {
+			numbers = append(numbers, number)
+		}
 	}
+	tickets = selectTickets(tickets, numbers)
 
-	return result
+	saved := make(map[int]Ticket, len(state.Tickets))
+	for _, ticket := range state.Tickets {
+		saved[ticket.Number] = ticket
+	}
+	for i, ticket := range tickets {
+		prev, ok := saved[ticket.Number]
+		if !ok {
+			continue
+		}
+		ticket.LogPath = prev.LogPath
+		ticket.Attempts = prev.Attempts
+		ticket.History = prev.History
+		ticket.RateLimits = prev.RateLimits
+		ticket.Uncounted = prev.Uncounted
+		if prev.Status == StatusRunning {
+			// Quitting or a crash interrupted the attempt
+			ticket.Uncounted++
+		}
+		if prev.finished() {
+			ticket.Status = prev.Status
+			ticket.ExitCode = prev.ExitCode
+			ticket.FailureReason = prev.FailureReason
+			ticket.StartTime = prev.StartTime
+			ticket.EndTime = prev.EndTime
+			ticket.Branch = prev.Branch
+			ticket.WorktreePath = prev.WorktreePath
+			ticket.CommitSHA = prev.CommitSHA
+			ticket.DiffStat = prev.DiffStat
+			ticket.NoOp = prev.NoOp
+			ticket.Report = prev.Report
+			ticket.VerifyLogPath = prev.VerifyLogPath
+		}
+		tickets[i] = ticket
+	}
+	return tickets
 }
 
-func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
-			if m.CurrentCmd != nil && m.CurrentCmd.Process != nil {
-				_ = m.CurrentCmd.Process.Kill()
-			}
-			return m, tea.Quit
+// resumeAgentWarning explains that a resumed run uses another agent than the
+// saved run did, or returns "" if it uses the same one.
+func (m Model) resumeAgentWarning() string {
+	if m.ResumeState == nil || m.ResumeState.AgentCommand == "" || m.ResumeState.AgentCommand == m.Agent.String() {
+		return ""
+	}
+	return fmt.Sprintf("The saved run used the agent %q, resuming with %q", m.ResumeState.AgentCommand, m.Agent.String())
+}
 
-		case "up", "k":
-			if m.State == StateProjectSelection {
-				if m.SelectedProjectIndex > 0 {
-					m.SelectedProjectIndex--
-				}
-			} else if m.State == StateAgentSelection {
-				m.SelectedAgent = (m.SelectedAgent - 1 + 2) % 2
-			}
+// failedTickets returns the numbers of the tickets that failed in the saved
+// run. A nil state has none.
+func (s *runState) failedTickets() map[int]bool {
+	failed := map[int]bool{}
+	if s == nil {
+		return failed
+	}
+	for _, ticket := range s.Tickets {
+		if ticket.Status.failed() {
+			failed[ticket.Number] = true
+		}
+	}
+	return failed
+}
 
-		case "down", "j":
+// selectedTickets returns the tickets chosen on the ticket selection screen,
+// in their current order.
+func (m Model) selectedTickets() []Ticket {
+	numbers := []int{}
+	for _, ticket := range m.Tickets {
+		if m.Selected[ticket.Number] {
+			numbers = append(numbers, ticket.Number)
+		}
+	}
+	return selectTickets(m.Tickets, numbers)
+}
+
+// selectTickets returns the tickets with the given numbers in that order.
+// Dependencies on tickets that are left out are dropped, as the run was
+// chosen without them.
+func selectTickets(tickets []Ticket, numbers []int) []Ticket {
+	byNumber := map[int]Ticket{}
+	for _, ticket := range tickets {
+		byNumber[ticket.Number] = ticket
+	}
+	chosen := map[int]bool{}
+	for _, number := range numbers {
+		chosen[number] = true
+	}
+
+	selected := []Ticket{}
+	for _, number := range numbers {
+		ticket := byNumber[number]
+		dependencies := []int{}
+		for _, dep := range ticket.Dependencies {
+			if _, known := byNumber[dep]; !known || chosen[dep] {
+				dependencies = append(dependencies, dep)
+			}
+		}
+		ticket.Dependencies = dependencies
+		selected = append(selected, ticket)
+	}
+	return selected
+}
+
+// parseTicketSelection reads a selection such as "3,1-2" into ticket numbers
+// in the order given. A range covers the tickets numbered within it, and a
+// ticket listed twice runs at its first position.
+func parseTicketSelection(spec string, tickets []Ticket) ([]int, error) {
+	exists := map[int]bool{}
+	for _, ticket := range tickets {
+		exists[ticket.Number] = true
+	}
+
+	numbers := []int{}
+	seen := map[int]bool{}
+	add := func(number int) {
+		if !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket %q", part)
+		}
+		if !isRange {
+			if !exists[first] {
+				return nil, fmt.Errorf("no ticket %d", first)
+			}
+			add(first)
+			continue
+		}
+		last, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil || last < first {
+			return nil, fmt.Errorf("invalid ticket range %q", part)
+		}
+		found := false
+		for _, ticket := range sortedByNumber(tickets) {
+			if ticket.Number >= first && ticket.Number <= last {
+				add(ticket.Number)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no tickets in range %q", part)
+		}
+	}
+	return numbers, nil
+}
+
+// sortedByNumber returns a copy of the tickets in numeric order.
+func sortedByNumber(tickets []Ticket) []Ticket {
+	sorted := append([]Ticket{}, tickets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Number < sorted[j].Number
+	})
+	return sorted
+}
+
+// nextUnfinishedTicket returns the index of the first unfinished ticket at or
+// after from, or len(tickets) when there is none.
+func nextUnfinishedTicket(tickets []Ticket, from int) int {
+	for i := from; i < len(tickets); i++ {
+		if !tickets[i].finished() {
+			return i
+		}
+	}
+	return len(tickets)
+}
+
+func checkFilesCmd(project string) tea.Cmd {
+	return func() tea.Msg {
+		return checkFiles(project)
+	}
+}
+
+func checkFiles(project string) fileCheckResult {
+	result := fileCheckResult{
+		MissingFiles:  []string{},
+		ParsedTickets: []Ticket{},
+	}
+
+	specPath := fmt.Sprintf("input/%s/specification.md", project)
+	ticketsPath := fmt.Sprintf("input/%s/tickets.md", project)
+	promptPath := fmt.Sprintf("input/%s/standard-prompt.md", project)
+
+	if _, err := os.Stat(specPath); os.IsNotExist(err) {
+		result.MissingFiles = append(result.MissingFiles, "specification.md")
+	} else {
+		result.SpecificationFound = true
+	}
+
+	if _, err := os.Stat(ticketsPath); os.IsNotExist(err) {
+		result.MissingFiles = append(result.MissingFiles, "tickets.md")
+	} else {
+		result.TicketsFound = true
+		// Parse tickets to get count
+		if tickets, err := parseTickets(ticketsPath); err == nil {
+			result.ParsedTickets = tickets
+			result.TicketCount = len(tickets)
+		}
+	}
+
+	if _, err := os.Stat(promptPath); os.IsNotExist(err) {
+		result.MissingFiles = append(result.MissingFiles, "standard-prompt.md")
+	} else {
+		result.StandardPromptFound = true
+		if content, err := os.ReadFile(promptPath); err != nil {
+			result.PromptError = err
+		} else if strings.Contains(string(content), "{{") {
+			// Render with placeholder data to catch unknown variables early
+			_, result.PromptError = renderPromptTemplate(promptPath, string(content), promptData{})
+		}
+	}
+
+	// Verification commands are optional
+	verifyPath := fmt.Sprintf("input/%s/verify.txt", project)
+	if commands, err := parseVerifyFile(verifyPath); err == nil {
+		result.VerifyCommands = commands
+	} else if !os.IsNotExist(err) {
+		result.VerifyError = err
+	}
+
+	// The last run tells which tickets failed before
+	result.PreviousRun, _ = loadRunState(project)
+
+	return result
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			for _, agent := range m.Running {
+				agent.stop()
+			}
+			return m, tea.Quit
+
+		case "up", "k":
+			if m.State == StateProjectSelection {
+				if m.SelectedProjectIndex > 0 {
+					m.SelectedProjectIndex--
+				}
+			} else if m.State == StateTicketSelection {
+				if m.SelectionCursor > 0 {
+					m.SelectionCursor--
+				}
+			} else if m.State == StateAgentSelection {
+				choices := len(m.Profiles) + 1
+				m.SelectedAgent = (m.SelectedAgent - 1 + choices) % choices
+			} else if m.State == StateRunning || m.State == StateCompleted {
+				if m.Cursor > 0 {
+					m.Cursor--
+					m.FollowOutput = true
+					m = m.refreshOutput()
+				}
+			}
+
+		case "down", "j":
 			if m.State == StateProjectSelection {
 				if m.SelectedProjectIndex < len(m.AvailableProjects)-1 {
 					m.SelectedProjectIndex++
 				}
+			} else if m.State == StateTicketSelection {
+				if m.SelectionCursor < len(m.Tickets)-1 {
+					m.SelectionCursor++
+				}
 			} else if m.State == StateAgentSelection {
-				m.SelectedAgent = (m.SelectedAgent + 1) % 2
+				m.SelectedAgent = (m.SelectedAgent + 1) % (len(m.Profiles) + 1)
+			} else if m.State == StateRunning || m.State == StateCompleted {
+				if m.Cursor < len(m.Tickets)-1 {
+					m.Cursor++
+					m.FollowOutput = true
+					m = m.refreshOutput()
+				}
+			}
+
+		case "r":
+			if m.State == StateConfirmation {
+				m.Retries = (m.Retries + 1) % (maxRetries + 1)
+			}
+			if (m.State == StateRunning || m.State == StateCompleted) && m.Cursor < len(m.Tickets) {
+				return m.retryTicket(m.Cursor)
+			}
+			if m.State == StateProjectSelection && m.SelectedProjectIndex < len(m.AvailableProjects) {
+				project := m.AvailableProjects[m.SelectedProjectIndex]
+				if state, ok := m.SavedRuns[project]; ok {
+					m = m.selectProject(project)
+					m.ResumeState = state
+					m.State = StateFileCheck
+					return m, checkFilesCmd(m.SelectedProject)
+				}
+			}
+
+		case "e":
+			if m.State == StateConfirmation {
+				m.ExitPolicy = m.ExitPolicy.next()
+			}
+
+		case "t":
+			if m.State == StateConfirmation {
+				m.Timeout = nextTimeout(m.Timeout)
+			}
+
+		case "c":
+			if m.State == StateConfirmation {
+				m.Concurrency = m.Concurrency%maxConcurrency + 1
+			}
+
+		case "w":
+			if m.State == StateConfirmation {
+				m.UseWorktrees = !m.UseWorktrees
+			}
+
+		case "a":
+			if m.State == StateConfirmation {
+				m.AutoCommit = !m.AutoCommit
+			}
+
+		case "p":
+			if m.State == StateConfirmation {
+				m.Agent.Prompt = m.Agent.Prompt.next()
+			}
+			if m.State == StateRunning {
+				m.Paused = !m.Paused
+				if !m.Paused && !m.IsWaiting {
+					return m.scheduleTickets()
+				}
+			}
+
+		case "s":
+			if m.State == StateRunning && m.Cursor < len(m.Tickets) {
+				return m.skipTicket(m.Cursor)
+			}
+
+		case "x":
+			if m.State == StateRunning {
+				return m.abortRun()
+			}
+
+		case "f":
+			if m.State == StateConfirmation {
+				m.WriteBackTickets = !m.WriteBackTickets
+			}
+			if m.State == StateTicketSelection {
+				m.OnlyFailed = !m.OnlyFailed
+				failed := m.PreviousRun.failedTickets()
+				for _, ticket := range m.Tickets {
+					m.Selected[ticket.Number] = !m.OnlyFailed || failed[ticket.Number]
+				}
+			}
+
+		case " ":
+			if m.State == StateTicketSelection && m.SelectionCursor < len(m.Tickets) {
+				number := m.Tickets[m.SelectionCursor].Number
+				m.Selected[number] = !m.Selected[number]
+			}
+
+		case "v":
+			// The first press marks where the range starts, the second selects it
+			if m.State == StateTicketSelection {
+				if m.SelectionAnchor < 0 {
+					m.SelectionAnchor = m.SelectionCursor
+				} else {
+					from, to := m.SelectionAnchor, m.SelectionCursor
+					if from > to {
+						from, to = to, from
+					}
+					for _, ticket := range m.Tickets[from : to+1] {
+						m.Selected[ticket.Number] = true
+					}
+					m.SelectionAnchor = -1
+				}
+			}
+
+		case "A":
+			if m.State == StateTicketSelection {
+				all := len(m.selectedTickets()) < len(m.Tickets)
+				for _, ticket := range m.Tickets {
+					m.Selected[ticket.Number] = all
+				}
+			}
+
+		case "K", "shift+up":
+			if m.State == StateTicketSelection && m.SelectionCursor > 0 {
+				i := m.SelectionCursor
+				m.Tickets[i-1], m.Tickets[i] = m.Tickets[i], m.Tickets[i-1]
+				m.SelectionCursor--
+			}
+
+		case "J", "shift+down":
+			if m.State == StateTicketSelection && m.SelectionCursor < len(m.Tickets)-1 {
+				i := m.SelectionCursor
+				m.Tickets[i+1], m.Tickets[i] = m.Tickets[i], m.Tickets[i+1]
+				m.SelectionCursor++
+			}
+
+		case "o":
+			if m.State == StateRunning {
+				m.ShowOutput = !m.ShowOutput
+			}
+
+		case "d":
+			if m.State == StateRunning || m.State == StateCompleted {
+				m.ShowDetails = !m.ShowDetails
+			}
+
+		case "pgup", "pgdown", "ctrl+u", "ctrl+d":
+			if m.State == StateRunning {
+				switch msg.String() {
+				case "pgup":
+					m.Output.ViewUp()
+				case "pgdown":
+					m.Output.ViewDown()
+				case "ctrl+u":
+					m.Output.HalfViewUp()
+				case "ctrl+d":
+					m.Output.HalfViewDown()
+				}
+				// Scrolling back to the end follows the output again
+				m.FollowOutput = m.Output.AtBottom()
+			}
+
+		case "G", "end":
+			if m.State == StateRunning {
+				m.Output.GotoBottom()
+				m.FollowOutput = true
+			}
+
+		case "F":
+			if m.State == StateRunning {
+				m.FollowOutput = !m.FollowOutput
+				if m.FollowOutput {
+					m.Output.GotoBottom()
+				}
+			}
+
+		case "y":
+			if (m.State == StateRunning || m.State == StateCompleted) && len(m.ProposedTickets) > 0 {
+				return m.approveProposedTicket()
+			}
+
+		case "n":
+			if (m.State == StateRunning || m.State == StateCompleted) && len(m.ProposedTickets) > 0 {
+				m.ProposedTickets = m.ProposedTickets[1:]
 			}
 
 		case "enter":
 			switch m.State {
 			case StateProjectSelection:
 				if len(m.AvailableProjects) > 0 && m.SelectedProjectIndex < len(m.AvailableProjects) {
-					m.SelectedProject = m.AvailableProjects[m.SelectedProjectIndex]
-					// Update file paths based on selected project
-					m.SpecificationPath = fmt.Sprintf("input/%s/specification.md", m.SelectedProject)
-					m.TicketsPath = fmt.Sprintf("input/%s/tickets.md", m.SelectedProject)
-					m.StandardPromptPath = fmt.Sprintf("input/%s/standard-prompt.md", m.SelectedProject)
+					m = m.selectProject(m.AvailableProjects[m.SelectedProjectIndex])
+					m.ResumeState = nil
 					m.State = StateFileCheck
 					return m, checkFilesCmd(m.SelectedProject)
 				}
 
+			case StateTicketSelection:
+				if selected := m.selectedTickets(); len(selected) > 0 {
+					m.Tickets = selected
+					m.State = StateAgentSelection
+				}
+
 			case StateAgentSelection:
-				if m.SelectedAgent == 0 {
-					m.CustomAgentCommand = "claude --dangerously-skip-permissions"
+				if m.SelectedAgent < len(m.Profiles) {
+					m.Agent = m.Profiles[m.SelectedAgent]
 					m.State = StateConfirmation
 				} else {
 					// Move to custom command entry state
@@ -266,12 +1462,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case StateCustomCommandEntry:
 				if m.TextInput.Value() != "" {
-					m.CustomAgentCommand = m.TextInput.Value()
-					m.State = StateConfirmation
+					agent, err := customProfile(m.TextInput.Value())
+					m.AgentError = err
+					if err == nil {
+						m.Agent = agent
+						m.State = StateConfirmation
+					}
 				}
 
 			case StateConfirmation:
-				m.ConfirmReady = true
+				m.ConfirmReady = m.commitSettingsError() == nil
 			}
 		}
 
@@ -284,8 +1484,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Handle any key press in StateFileCheckResults
 		if m.State == StateFileCheckResults {
-			// Any key press moves to agent selection
-			return m.Update(proceedToAgentSelectionMsg{})
+			// A broken template has to be fixed first, check the files again
+			if m.PromptError != nil || m.VerifyError != nil {
+				return m, checkFilesCmd(m.SelectedProject)
+			}
+			// Any key press moves on to the ticket selection
+			return m.Update(proceedToTicketSelectionMsg{})
 		}
 
 		// Handle file picker navigation
@@ -305,7 +1509,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				m.CurrentMissingIndex++
 				if m.CurrentMissingIndex >= len(m.MissingFiles) {
-					m.State = StateAgentSelection
+					return m.Update(proceedToTicketSelectionMsg{})
 				} else {
 					fp := filepicker.New()
 					fp.CurrentDirectory, _ = os.Getwd()
@@ -317,128 +1521,300 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tickMsg:
-		if msg.err != nil {
-			m.ProcessError = msg.err
-			m.ProcessRunning = false
-
-			// Check if it's an API overload error and increase delay
-			if strings.Contains(msg.output, "server overload") ||
-				strings.Contains(msg.output, "rate limit") ||
-				strings.Contains(msg.output, "too many requests") {
-				// Exponential backoff: double the delay, max 30 seconds
-				m.DelaySeconds *= 2
-				if m.DelaySeconds > 30 {
-					m.DelaySeconds = 30
-				}
-			}
-			// Continue to next agent even on error (after delay)
-			return m.Update(processCompleteMsg{})
-		}
-		// If we got output but no error, the process succeeded
-		// Reset delay to default on success
-		m.DelaySeconds = 2
-		return m.Update(processCompleteMsg{})
+		// The agent could not be started, continue with the next one
+		return m.Update(processCompleteMsg{ticket: msg.ticket, status: StatusFailed, err: msg.err})
 
 	case processStartedMsg:
+		agent, ok := m.Running[msg.ticket]
+		if !ok {
+			// The ticket was skipped or the run aborted while it started
+			killProcess(msg.cmd)
+			removeFile(msg.promptFile)
+			return m, nil
+		}
 		// Store the running command
-		m.CurrentCmd = msg.cmd
+		agent.Cmd = msg.cmd
+		agent.PromptFile = msg.promptFile
+		m.Tickets[msg.ticket].LogPath = msg.logPath
+		m.Tickets[msg.ticket].Branch = msg.branch
+		m.Tickets[msg.ticket].WorktreePath = msg.worktreePath
 		// Record start time for this ticket
-		m.Tickets[m.CurrentTicket].StartTime = time.Now()
-		// Start monitoring for kill file and update timer
-		return m, tea.Batch(
-			checkForKillFile(),
-			// Update the view every second to show live duration
-			tea.Tick(time.Second, func(t time.Time) tea.Msg {
-				return t
-			}),
-		)
+		m.Tickets[msg.ticket].StartTime = time.Now()
+		// Follow the run to the new agent unless another one is being watched
+		if _, watching := m.Running[m.Cursor]; m.FollowOutput && (!watching || m.Cursor == msg.ticket) {
+			m.Cursor = msg.ticket
+			m = m.refreshOutput()
+		}
+		// Start monitoring for kill file and process exit
+		cmds := []tea.Cmd{
+			checkForKillFile(msg.ticket, msg.cmd),
+			waitForExit(msg.ticket, msg.cmd),
+		}
+		if timeout := m.ticketTimeout(m.Tickets[msg.ticket]); timeout > 0 {
+			cmds = append(cmds, tea.Tick(timeout, func(t time.Time) tea.Msg {
+				return ticketTimeoutMsg{ticket: msg.ticket, cmd: msg.cmd, timeout: timeout}
+			}))
+		}
+		return m, tea.Batch(cmds...)
+
+	case ticketTimeoutMsg:
+		// The agent finished in time
+		agent := m.agentFor(msg.ticket, msg.cmd)
+		if agent == nil || agent.Terminating {
+			return m, nil
+		}
+		// Ask the agent to shut down and kill it if it ignores us
+		agent.Terminating = true
+		m.Tickets[msg.ticket].FailureReason = fmt.Sprintf("timed out after %s", msg.timeout)
+		terminateProcess(msg.cmd)
+		return m, tea.Tick(m.GracePeriod, func(t time.Time) tea.Msg {
+			return killEscalationMsg{ticket: msg.ticket, cmd: msg.cmd}
+		})
+
+	case killEscalationMsg:
+		// Still running after the grace period
+		if m.agentFor(msg.ticket, msg.cmd) != nil {
+			killProcess(msg.cmd)
+		}
+		return m, nil
 
 	case checkKillFileMsg:
-		// Check if killmenow.md exists
-		if content, err := os.ReadFile("killmenow.md"); err == nil {
+		// Stop polling once the agent has been handled or timed out
+		agent := m.agentFor(msg.ticket, msg.cmd)
+		if agent == nil || agent.Terminating {
+			return m, nil
+		}
+		// Check if the kill file exists. An empty file or a partial JSON report
+		// is still being written by the agent, so wait for its content.
+		killFile := m.killFilePath(m.Tickets[msg.ticket])
+		if content, err := os.ReadFile(killFile); err == nil && reportComplete(string(content)) {
 			// File found, return the content
-			return m.Update(killFileFoundMsg{content: string(content)})
+			return m.Update(killFileFoundMsg{ticket: msg.ticket, content: string(content)})
 		}
 		// File not found, check again in 500ms
 		return m, tea.Tick(500*time.Millisecond, func(t time.Time) tea.Msg {
-			return checkKillFileMsg{}
+			return checkKillFileMsg(msg)
 		})
 
+	case processExitedMsg:
+		// Ignore agents that were already handled via their kill file
+		agent := m.agentFor(msg.ticket, msg.cmd)
+		if agent == nil {
+			return m, nil
+		}
+
+		// The agent may have written its kill file right before exiting
+		killFile := m.killFilePath(m.Tickets[msg.ticket])
+		if content, err := os.ReadFile(killFile); err == nil && !agent.Terminating {
+			return m.Update(killFileFoundMsg{ticket: msg.ticket, content: string(content)})
+		}
+
+		ticket := &m.Tickets[msg.ticket]
+		ticket.ExitCode = msg.exitCode
+
+		// A rate limited ticket is not burned but started again later
+		if !agent.Terminating && msg.err == nil && ticket.RateLimits < maxRateLimitRetries {
+			if reason, wait, limited := m.detectRateLimit(*ticket, msg.exitCode); limited {
+				return m.Update(processCompleteMsg{ticket: msg.ticket, status: StatusRateLimited, err: errors.New(reason), wait: wait})
+			}
+		}
+
+		var err error
+		status := StatusSucceeded
+		switch {
+		case agent.Terminating:
+			status, err = StatusTimedOut, errors.New(ticket.FailureReason)
+		case msg.err != nil:
+			status, err = StatusCrashed, msg.err
+		case msg.exitCode != 0:
+			status, err = StatusCrashed, fmt.Errorf("agent exited with code %d", msg.exitCode)
+		case m.ExitPolicy == ExitPolicySuccess:
+		case m.ExitPolicy == ExitPolicyFailure:
+			status, err = StatusFailed, fmt.Errorf("agent exited without writing %s", killFile)
+		default:
+			status = StatusUnknown
+		}
+		return m.finishTicket(msg.ticket, status, err)
+
 	case killFileFoundMsg:
 		// Kill the process
-		if m.CurrentCmd != nil && m.CurrentCmd.Process != nil {
-			_ = m.CurrentCmd.Process.Kill()
-			m.CurrentCmd = nil
+		if agent, ok := m.Running[msg.ticket]; ok {
+			killProcess(agent.Cmd)
 		}
 
 		// Delete the kill file
-		_ = os.Remove("killmenow.md")
+		_ = os.Remove(m.killFilePath(m.Tickets[msg.ticket]))
 
 		// Determine success or failure
-		if strings.Contains(strings.ToLower(msg.content), "success") {
-			m.ProcessError = nil
-		} else {
-			m.ProcessError = fmt.Errorf("agent reported failure")
+		report, err := parseCompletionReport(msg.content)
+		if err == nil {
+			m.Tickets[msg.ticket].Report = &report
+			err = report.err()
+			for _, proposal := range report.NewTickets {
+				m.ProposedTickets = append(m.ProposedTickets, Ticket{
+					Description: proposal.Description,
+					Body:        strings.TrimSpace(proposal.Body),
+					ProposedBy:  m.Tickets[msg.ticket].Number,
+				})
+			}
+			// Approved tickets must be added before the run can complete
+			for m.AutoApprove && len(m.ProposedTickets) > 0 {
+				next, _ := m.approveProposedTicket()
+				m = next.(Model)
+			}
 		}
 
 		// Move to completion
-		return m.Update(processCompleteMsg{})
+		if err != nil {
+			return m.finishTicket(msg.ticket, StatusFailed, err)
+		}
+		return m.finishTicket(msg.ticket, StatusSucceeded, nil)
 
-	case processCompleteMsg:
-		m.ProcessRunning = false
+	case ticketVerifiedMsg:
+		// The ticket was skipped, retried or aborted in the meantime
+		agent, ok := m.Running[msg.ticket]
+		if !ok || agent.Cmd != msg.cmd || !agent.Verifying {
+			return m, nil
+		}
+		agent.Verifying = false
+		m.Tickets[msg.ticket].VerifyLogPath = msg.logPath
+		if msg.err != nil {
+			return m.Update(processCompleteMsg{ticket: msg.ticket, status: StatusVerifyFailed, err: msg.err})
+		}
+		agent.Verified = true
+		return m.finishTicket(msg.ticket, StatusSucceeded, nil)
 
-		// Record end time for this ticket
-		m.Tickets[m.CurrentTicket].EndTime = time.Now()
+	case verifyCommandDoneMsg:
+		// The verification was stopped or timed out in the meantime
+		agent, ok := m.Running[msg.ticket]
+		if !ok || agent.VerifyCmd != msg.cmd {
+			return m, nil
+		}
+		agent.VerifyCmd = nil
+		if msg.err != nil || msg.step+1 == len(m.verifyCommands(m.Tickets[msg.ticket])) {
+			return m.Update(ticketVerifiedMsg{ticket: msg.ticket, cmd: agent.Cmd, logPath: msg.logPath, err: msg.err})
+		}
+		return m.startVerifyCommand(msg.ticket, msg.step+1, msg.logPath)
 
-		// Mark ticket as completed or failed based on error state
-		if m.ProcessError != nil {
-			m.Tickets[m.CurrentTicket].Failed = true
-		} else {
-			m.Tickets[m.CurrentTicket].Completed = true
+	case verifyTimeoutMsg:
+		// The verification finished in time
+		agent, ok := m.Running[msg.ticket]
+		if !ok || agent.Cmd != msg.cmd || !agent.Verifying {
+			return m, nil
 		}
+		killProcess(agent.VerifyCmd)
+		agent.VerifyCmd = nil
+		return m.Update(ticketVerifiedMsg{ticket: msg.ticket, cmd: msg.cmd, logPath: msg.logPath,
+			err: fmt.Errorf("verification timed out after %s", msg.timeout)})
 
-		m.CurrentTicket++
+	case ticketCommittedMsg:
+		if agent, ok := m.Running[msg.ticket]; !ok || agent.Cmd != msg.cmd || !agent.Committing {
+			return m, nil
+		}
+		ticket := &m.Tickets[msg.ticket]
+		if msg.err != nil {
+			return m.Update(processCompleteMsg{ticket: msg.ticket, status: StatusFailed, err: msg.err})
+		}
+		ticket.CommitSHA = msg.sha
+		ticket.DiffStat = msg.diffStat
+		ticket.NoOp = msg.sha == ""
+		return m.Update(processCompleteMsg{ticket: msg.ticket, status: StatusSucceeded})
 
-		if m.CurrentTicket < len(m.Tickets) {
-			// Don't clear output yet - keep it visible during waiting
-			// We'll clear it when we actually start the next agent
+	case processCompleteMsg:
+		// The ticket was skipped, retried or aborted in the meantime
+		agent, ok := m.Running[msg.ticket]
+		if !ok {
+			return m, nil
+		}
+		agent.removePromptFile()
+		delete(m.Running, msg.ticket)
+		ticket := &m.Tickets[msg.ticket]
 
-			// Start waiting period
-			m.IsWaiting = true
-			m.WaitingUntil = time.Now().Add(time.Duration(m.DelaySeconds) * time.Second)
-			m.ProcessRunning = false
+		// Record end time for this ticket. An agent that failed to start has
+		// no start time and took no time at all.
+		ticket.EndTime = time.Now()
+		if ticket.StartTime.IsZero() {
+			ticket.StartTime = ticket.EndTime
+		}
 
-			// Return commands for both the waiting timer and the countdown update
-			return m, tea.Batch(
-				tea.Tick(time.Duration(m.DelaySeconds)*time.Second, func(t time.Time) tea.Msg {
-					return waitingDoneMsg{}
-				}),
-				tea.Tick(time.Second, func(t time.Time) tea.Msg {
-					return t
-				}),
-			)
-		} else {
+		// Record the attempt's outcome. A failed ticket with retries left goes
+		// back to pending and is scheduled again.
+		ticket.FailureReason = ""
+		if msg.err != nil {
+			ticket.FailureReason = msg.err.Error()
+		}
+		ticket.History = append(ticket.History, Attempt{
+			Number:        ticket.Attempts,
+			StartTime:     ticket.StartTime,
+			EndTime:       ticket.EndTime,
+			Status:        msg.status,
+			FailureReason: ticket.FailureReason,
+			ExitCode:      ticket.ExitCode,
+			LogPath:       ticket.LogPath,
+			VerifyLogPath: ticket.VerifyLogPath,
+		})
+		status := msg.status
+		if status == StatusRateLimited {
+			ticket.RateLimits++
+		} else if status.failed() {
+			m.ProcessError = msg.err
+			if ticket.countedAttempts() <= m.ticketRetries(*ticket) {
+				status = StatusPending
+			}
+		}
+		m.setStatus(msg.ticket, status)
+
+		// Persist progress so the run can be resumed after a crash or quit
+		_ = m.saveRunState()
+
+		// A rate limit holds back every agent, not only the limited ticket
+		wait := time.Duration(m.DelaySeconds) * time.Second
+		waitReason := ""
+		if msg.status == StatusRateLimited {
+			m.RateLimitHits++
+			wait = msg.wait
+			waitReason = fmt.Sprintf("%s, retrying ticket %d", ticket.FailureReason, ticket.Number)
+		} else if msg.err == nil {
+			m.RateLimitHits = 0
+		}
+		until := time.Now().Add(wait)
+		if m.IsWaiting && !until.After(m.WaitingUntil) {
+			return m, nil
+		}
+		if nextUnfinishedTicket(m.Tickets, 0) >= len(m.Tickets) {
 			m.State = StateCompleted
+			return m, nil
 		}
-		return m, nil
+
+		// Start waiting period before launching further agents
+		m.IsWaiting = true
+		m.WaitingUntil = until
+		m.WaitReason = waitReason
+		return m, tea.Tick(wait, func(t time.Time) tea.Msg {
+			return waitingDoneMsg{until: until}
+		})
 
 	case waitingDoneMsg:
-		// Waiting period is over, start next agent
+		// A longer wait for a rate limit replaced this one
+		if msg.until.Before(m.WaitingUntil) {
+			return m, nil
+		}
+		// Waiting period is over, start the next agents
 		m.IsWaiting = false
+		m.WaitReason = ""
+		if m.State != StateRunning {
+			return m, nil
+		}
 
 		// Clear error state for next agent
 		m.ProcessError = nil
-		m.ProcessRunning = true
-		return m, m.runNextAgent()
+		return m.scheduleTickets()
 
 	case time.Time:
-		// Update the view to refresh the countdown or running time
-		if m.IsWaiting && time.Now().Before(m.WaitingUntil) {
-			return m, tea.Tick(time.Second, func(t time.Time) tea.Msg {
-				return t
-			})
-		} else if m.ProcessRunning && m.State == StateRunning {
-			// Continue updating while a process is running to show live duration
+		// Update the view every second to refresh the countdown and the
+		// running times
+		if m.State == StateRunning {
+			m = m.refreshOutput()
 			return m, tea.Tick(time.Second, func(t time.Time) tea.Msg {
 				return t
 			})
@@ -447,6 +1823,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case projectsScannedMsg:
 		m.AvailableProjects = msg.projects
+		m.SavedRuns = msg.savedRuns
+		if msg.profiles != nil {
+			m.Profiles = msg.profiles
+		}
+		m.AgentError = msg.profilesErr
 		if len(m.AvailableProjects) == 0 {
 			// No projects found - we could show an error
 			m.ProcessError = fmt.Errorf("No project folders found in input/")
@@ -460,6 +1841,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if len(msg.ParsedTickets) > 0 {
 			m.Tickets = msg.ParsedTickets
 		}
+		if m.ResumeState != nil {
+			m.Tickets = mergeRunState(m.Tickets, m.ResumeState)
+			m.RunDir = m.ResumeState.RunDir
+		}
+		m.PromptError = msg.PromptError
+		m.VerifyCommands = msg.VerifyCommands
+		m.VerifyError = msg.VerifyError
+		m.StaleKillFiles = findStaleKillFiles(m.RunDir)
+		m.PreviousRun = msg.PreviousRun
 
 		if len(msg.MissingFiles) == 0 {
 			// All files found - show results and wait for user input
@@ -477,51 +1867,518 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.FilePicker.Init()
 		}
 
-	case proceedToAgentSelectionMsg:
-		// Transition from file check results to agent selection
-		if m.State == StateFileCheckResults {
-			// Tickets are already parsed during file check, no need to re-parse
+	case proceedToTicketSelectionMsg:
+		// Tickets are already parsed during file check, no need to re-parse.
+		// A resumed run keeps its tickets, and without any there is nothing
+		// to choose from.
+		if m.ResumeState != nil || len(m.Tickets) == 0 {
 			m.State = StateAgentSelection
+			return m, nil
+		}
+		m.State = StateTicketSelection
+		m.Selected = map[int]bool{}
+		for _, ticket := range m.Tickets {
+			m.Selected[ticket.Number] = true
 		}
+		m.SelectionCursor = 0
+		m.SelectionAnchor = -1
+		m.OnlyFailed = false
 		return m, nil
 
 	case tea.WindowSizeMsg:
 		m.Width = msg.Width
 		m.Height = msg.Height
+		m = m.resizeOutput()
 	}
 
 	// Update states
 	switch m.State {
 	case StateConfirmation:
 		if m.ConfirmReady {
-			m.State = StateRunning
-			m.ProcessRunning = true
-			m.CurrentTicket = 0
-			return m, m.runNextAgent()
+			return m.startRun()
 		}
-		m.ConfirmReady = true
 	}
 
 	return m, nil
 }
 
-func parseTickets(path string) ([]Ticket, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// selectProject points the model's file paths at the given project folder.
+func (m Model) selectProject(project string) Model {
+	m.SelectedProject = project
+	m.SpecificationPath = fmt.Sprintf("input/%s/specification.md", project)
+	m.TicketsPath = fmt.Sprintf("input/%s/tickets.md", project)
+	m.StandardPromptPath = fmt.Sprintf("input/%s/standard-prompt.md", project)
+	return m
+}
+
+// startRun switches to StateRunning and launches the first agent. It is shared
+// by the confirmation screen and the headless runner.
+func (m Model) startRun() (Model, tea.Cmd) {
+	if len(m.Tickets) == 0 {
+		m.State = StateCompleted
+		return m, nil
+	}
+	m.State = StateRunning
+	m.Running = map[int]*runningAgent{}
+	if m.Concurrency < 1 {
+		m.Concurrency = 1
+	}
+	if m.RunDir == "" {
+		m.RunDir = filepath.Join("runs", m.SelectedProject, time.Now().Format("20060102-150405"))
+	}
+	// A stale kill file must not complete a ticket that has not even started
+	for _, path := range findStaleKillFiles(m.RunDir) {
+		_ = os.Remove(path)
+	}
+	m.StaleKillFiles = nil
+	// Resumed runs continue with the first unfinished ticket
+	if next := nextUnfinishedTicket(m.Tickets, 0); next < len(m.Tickets) {
+		m.Cursor = next
+	}
+
+	m, cmd := m.scheduleTickets()
+	return m, tea.Batch(cmd, tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return t
+	}))
+}
+
+// maxRetries is the highest number of retries offered in the TUI.
+const maxRetries = 3
+
+// maxConcurrency is the highest number of parallel agents offered in the TUI.
+const maxConcurrency = 4
+
+// scheduleTickets starts agents for the tickets whose dependencies are met, up
+// to the concurrency limit. It completes the run once nothing is running and
+// nothing is left to start.
+func (m Model) scheduleTickets() (Model, tea.Cmd) {
+	m.blockUnsatisfiableTickets()
+	if m.Paused {
+		if len(m.Running) == 0 && nextUnfinishedTicket(m.Tickets, 0) >= len(m.Tickets) {
+			_ = m.saveRunState()
+			m.State = StateCompleted
+		}
+		return m, nil
+	}
+
+	var cmds []tea.Cmd
+	for i := range m.Tickets {
+		if len(m.Running) >= m.Concurrency {
+			break
+		}
+		if m.ticketReady(i) && m.setStatus(i, StatusRunning) {
+			m.Running[i] = &runningAgent{}
+			ticket := &m.Tickets[i]
+			ticket.Attempts++
+			// Results of the previous attempt are kept in its history
+			ticket.ExitCode = 0
+			ticket.Report = nil
+			ticket.VerifyLogPath = ""
+			cmds = append(cmds, m.runAgent(i))
+		}
+	}
+
+	if len(m.Running) == 0 {
+		// Whatever is left waits on tickets that will never run
+		for i := range m.Tickets {
+			if !m.Tickets[i].finished() {
+				m.blockTicket(i, "dependencies form a cycle")
+			}
+		}
+		_ = m.saveRunState()
+		m.State = StateCompleted
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// approveProposedTicket adds the oldest proposed ticket to the run with the
+// next free ticket number. A completed run continues with the new ticket.
+func (m Model) approveProposedTicket() (tea.Model, tea.Cmd) {
+	ticket := m.ProposedTickets[0]
+	m.ProposedTickets = m.ProposedTickets[1:]
+
+	// Tickets left out of the run still own their numbers in tickets.md
+	existingTickets := append([]Ticket{}, m.Tickets...)
+	if parsed, err := parseTickets(m.TicketsPath); err == nil {
+		existingTickets = append(existingTickets, parsed...)
+	}
+	for _, existing := range existingTickets {
+		if existing.Number >= ticket.Number {
+			ticket.Number = existing.Number + 1
+		}
+	}
+	m.Tickets = append(m.Tickets, ticket)
+	if m.WriteBackTickets {
+		if err := appendTicket(m.TicketsPath, ticket); err != nil {
+			m.ProcessError = fmt.Errorf("writing %s: %w", m.TicketsPath, err)
+		}
+	}
+	_ = m.saveRunState()
+
+	if m.State != StateCompleted {
+		return m, nil
+	}
+	return m.resumeRun()
+}
+
+// resumeRun continues a completed or aborted run, for example after a ticket
+// was added or retried.
+func (m Model) resumeRun() (tea.Model, tea.Cmd) {
+	m.State = StateRunning
+	m.Aborted = false
+	m, cmd := m.scheduleTickets()
+	return m, tea.Batch(cmd, tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return t
+	}))
+}
+
+// stopTicket stops the agent working on a ticket and records the attempt with
+// the given status. It reports false for an agent that cannot be stopped yet
+// because its process is still starting.
+func (m Model) stopTicket(i int, status TicketStatus, reason string) bool {
+	agent, ok := m.Running[i]
+	if !ok {
+		return true
+	}
+	if agent.Cmd == nil {
+		return false
+	}
+	agent.stop()
+	delete(m.Running, i)
+	ticket := &m.Tickets[i]
+	_ = os.Remove(m.killFilePath(*ticket))
+	ticket.EndTime = time.Now()
+	ticket.History = append(ticket.History, Attempt{
+		Number:        ticket.Attempts,
+		StartTime:     ticket.StartTime,
+		EndTime:       ticket.EndTime,
+		Status:        status,
+		FailureReason: reason,
+		LogPath:       ticket.LogPath,
+	})
+	return true
+}
+
+// skipTicket gives up on a ticket, stopping its agent if it is running.
+// Tickets depending on it are blocked.
+func (m Model) skipTicket(i int) (tea.Model, tea.Cmd) {
+	if !m.Tickets[i].Status.canBecome(StatusSkipped) || !m.stopTicket(i, StatusSkipped, "skipped") {
+		return m, nil
+	}
+	ticket := &m.Tickets[i]
+	m.setStatus(i, StatusSkipped)
+	ticket.FailureReason = "skipped"
+	if ticket.StartTime.IsZero() {
+		ticket.StartTime = time.Now()
+		ticket.EndTime = ticket.StartTime
+	}
+	_ = m.saveRunState()
+	if m.IsWaiting {
+		return m, nil
+	}
+	return m.scheduleTickets()
+}
+
+// retryTicket runs a ticket again: a running agent is restarted, a finished
+// ticket is queued with its results cleared. Tickets that were blocked
+// without ever running get another chance as well.
+func (m Model) retryTicket(i int) (tea.Model, tea.Cmd) {
+	ticket := &m.Tickets[i]
+	if !ticket.Status.canBecome(StatusPending) || !m.stopTicket(i, StatusCancelled, "restarted") {
+		return m, nil
+	}
+	m.setStatus(i, StatusPending)
+	ticket.Uncounted = ticket.Attempts - ticket.RateLimits
+	ticket.CommitSHA = ""
+	ticket.DiffStat = ""
+	ticket.NoOp = false
+	ticket.FailureReason = "retry requested"
+	for j := range m.Tickets {
+		if m.Tickets[j].Status == StatusBlocked {
+			m.setStatus(j, StatusPending)
+			m.Tickets[j].FailureReason = ""
+		}
+	}
+	_ = m.saveRunState()
+
+	if m.State == StateCompleted {
+		return m.resumeRun()
+	}
+	if m.IsWaiting {
+		return m, nil
+	}
+	return m.scheduleTickets()
+}
+
+// abortRun stops every agent and ends the run early. Interrupted tickets stay
+// unfinished, so the run can be resumed later.
+func (m Model) abortRun() (tea.Model, tea.Cmd) {
+	for i, agent := range m.Running {
+		if agent.Cmd == nil {
+			// Killed as soon as it reports that it started
+			delete(m.Running, i)
+		} else {
+			m.stopTicket(i, StatusCancelled, "run aborted")
+		}
+		// The interrupted attempt does not count against the retries
+		m.Tickets[i].Uncounted++
+	}
+	for i := range m.Tickets {
+		if m.setStatus(i, StatusCancelled) {
+			m.Tickets[i].FailureReason = "run aborted"
+		}
+	}
+	m.Aborted = true
+	m.Paused = false
+	m.IsWaiting = false
+	m.WaitReason = ""
+	_ = m.saveRunState()
+	m.State = StateCompleted
+	return m, nil
+}
+
+// appendTicket writes an approved ticket to the end of tickets.md.
+func appendTicket(path string, ticket Ticket) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("\n## Ticket %d: %s\n", ticket.Number, ticket.Description)
+	if ticket.Body != "" {
+		content += ticket.Body + "\n"
+	}
+	if _, err := file.WriteString(content); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ticketIndex returns the position of the ticket with the given number, or -1.
+func (m Model) ticketIndex(number int) int {
+	for i, ticket := range m.Tickets {
+		if ticket.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+// ticketReady reports whether a ticket can start: it has no result yet, is
+// not running and every dependency has finished without failing.
+func (m Model) ticketReady(i int) bool {
+	ticket := m.Tickets[i]
+	if ticket.finished() {
+		return false
+	}
+	if _, running := m.Running[i]; running {
+		return false
+	}
+	for _, dep := range ticket.Dependencies {
+		j := m.ticketIndex(dep)
+		if j < 0 || !m.Tickets[j].finished() || m.Tickets[j].Status.failed() || m.Tickets[j].Status == StatusSkipped {
+			return false
+		}
+	}
+	return true
+}
+
+// pendingDependencies lists the dependencies of a ticket that have not
+// finished yet.
+func (m Model) pendingDependencies(ticket Ticket) []int {
+	pending := []int{}
+	for _, dep := range ticket.Dependencies {
+		if j := m.ticketIndex(dep); j >= 0 && !m.Tickets[j].finished() {
+			pending = append(pending, dep)
+		}
+	}
+	return pending
+}
+
+// blockUnsatisfiableTickets blocks every unfinished ticket that depends on a
+// failed or missing ticket. Blocking cascades to their own dependents.
+func (m Model) blockUnsatisfiableTickets() {
+	for changed := true; changed; {
+		changed = false
+		for i, ticket := range m.Tickets {
+			if ticket.finished() {
+				continue
+			}
+			if _, running := m.Running[i]; running {
+				continue
+			}
+			for _, dep := range ticket.Dependencies {
+				j := m.ticketIndex(dep)
+				if j < 0 {
+					m.blockTicket(i, fmt.Sprintf("depends on unknown ticket %d", dep))
+				} else if m.Tickets[j].Status.failed() {
+					m.blockTicket(i, fmt.Sprintf("blocked by failed ticket %d", dep))
+				} else if m.Tickets[j].Status == StatusSkipped {
+					m.blockTicket(i, fmt.Sprintf("blocked by skipped ticket %d", dep))
+				} else {
+					continue
+				}
+				changed = true
+				break
+			}
+		}
+	}
+}
+
+// blockTicket marks a ticket that can never run as blocked. It keeps zero
+// start and end times since no agent worked on it.
+func (m Model) blockTicket(i int, reason string) {
+	if m.setStatus(i, StatusBlocked) {
+		m.Tickets[i].FailureReason = reason
+	}
+}
+
+// setStatus moves a ticket to a new status if the transition is valid, and
+// reports whether it did. Late messages about a ticket that was skipped or
+// retried in the meantime can then never overwrite its status.
+func (m Model) setStatus(i int, status TicketStatus) bool {
+	if !m.Tickets[i].Status.canBecome(status) {
+		return false
+	}
+	m.Tickets[i].Status = status
+	return true
+}
+
+// agentFor returns the running agent of a ticket if it is still the given
+// process, so that messages about agents that were already handled are
+// ignored.
+func (m Model) agentFor(ticket int, cmd *exec.Cmd) *runningAgent {
+	agent, ok := m.Running[ticket]
+	if !ok || agent.Cmd != cmd || agent.Committing || agent.Verifying {
+		return nil
+	}
+	return agent
+}
+
+// finishTicket completes a ticket whose agent is done. A successful ticket
+// has to pass its verification commands first, and with auto-commit on its
+// changes are committed before it completes.
+func (m Model) finishTicket(index int, status TicketStatus, err error) (tea.Model, tea.Cmd) {
+	agent, ok := m.Running[index]
+	if status != StatusSucceeded || !ok {
+		return m.Update(processCompleteMsg{ticket: index, status: status, err: err})
+	}
+	ticket := m.Tickets[index]
+
+	// Ignore the agent's remaining messages while verifying and committing
+	if commands := m.verifyCommands(ticket); len(commands) > 0 && !agent.Verified {
+		agent.Verifying = true
+		logPath := filepath.Join(m.RunDir, fmt.Sprintf("ticket-%d%s.verify.log", ticket.Number, attemptSuffix(ticket)))
+		// The verification gets the ticket's timeout on top of the agent's
+		var timeoutCmd tea.Cmd
+		if timeout := m.ticketTimeout(ticket); timeout > 0 {
+			agentCmd := agent.Cmd
+			timeoutCmd = tea.Tick(timeout, func(t time.Time) tea.Msg {
+				return verifyTimeoutMsg{ticket: index, cmd: agentCmd, logPath: logPath, timeout: timeout}
+			})
+		}
+		next, cmd := m.startVerifyCommand(index, 0, logPath)
+		return next, tea.Batch(cmd, timeoutCmd)
+	}
+	if !m.AutoCommit {
+		return m.Update(processCompleteMsg{ticket: index, status: StatusSucceeded})
+	}
+	agent.Committing = true
+	cmd := agent.Cmd
+	return m, func() tea.Msg {
+		sha, diffStat, err := commitTicket(ticket)
+		return ticketCommittedMsg{ticket: index, cmd: cmd, sha: sha, diffStat: diffStat, err: err}
+	}
+}
+
+// verifyCommands returns the project's verification commands followed by the
+// ticket's own.
+func (m Model) verifyCommands(ticket Ticket) []string {
+	return append(append([]string{}, m.VerifyCommands...), ticket.Verify...)
+}
+
+// startVerifyCommand runs the verification command at position step in the
+// ticket's directory, in its own process group so that it can be stopped
+// together with its children. The first command truncates the log at logPath,
+// later ones append to it. Verification stops at the first failing command.
+func (m Model) startVerifyCommand(index, step int, logPath string) (tea.Model, tea.Cmd) {
+	agent := m.Running[index]
+	ticket := m.Tickets[index]
+	command := m.verifyCommands(ticket)[step]
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if step == 0 {
+		flags |= os.O_TRUNC
+	}
+	logFile, err := os.OpenFile(logPath, flags, 0o644)
+	if err != nil {
+		return m.Update(ticketVerifiedMsg{ticket: index, cmd: agent.Cmd, logPath: logPath, err: fmt.Errorf("creating verification log: %w", err)})
+	}
+
+	fmt.Fprintf(logFile, "$ %s\n", command)
+	cmd := shellCommand(command)
+	setProcessGroup(cmd)
+	cmd.Dir = m.agentDir(ticket)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(logFile, "%v\n", err)
+		_ = logFile.Close()
+		return m.Update(ticketVerifiedMsg{ticket: index, cmd: agent.Cmd, logPath: logPath, err: fmt.Errorf("verification failed: %s: %v", command, err)})
+	}
+	agent.VerifyCmd = cmd
+	return m, func() tea.Msg {
+		err := cmd.Wait()
+		if err != nil {
+			fmt.Fprintf(logFile, "%v\n", err)
+			err = fmt.Errorf("verification failed: %s: %v", command, err)
+		}
+		_ = logFile.Close()
+		return verifyCommandDoneMsg{ticket: index, cmd: cmd, step: step, logPath: logPath, err: err}
+	}
+}
+
+// parseVerifyFile reads one verification command per line. Blank lines and
+// lines starting with # are skipped.
+func parseVerifyFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	commands := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			commands = append(commands, line)
+		}
+	}
+	return commands, nil
+}
+
+func parseTickets(path string) ([]Ticket, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create regex patterns for different ticket formats
 	// Matches: # Ticket 1, ## Ticket 2:, ### ticket 3 -, #### TICKET #4, etc.
 	// Also matches tickets without numbers: ## Ticket: Description
 	ticketRegex := regexp.MustCompile(`(?i)^#+\s*ticket\s*(?:#?\s*(\d+))?\s*[:|\-–—]?\s*(.*)`)
+	// Matches a metadata line below the heading: Timeout: 45m
+	metadataRegex := regexp.MustCompile(`^([A-Za-z][A-Za-z ]*?)\s*:\s*(.*)$`)
 
 	lines := strings.Split(string(content), "\n")
 	tickets := []Ticket{}
 	ticketMap := make(map[int]bool) // To avoid duplicate ticket numbers
+	bodies := [][]string{}          // Raw body lines per ticket, in file order
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	// The metadata block directly below a heading is either a run of
+	// "Key: value" lines or front matter fenced by "---" lines
+	inMetadata, inFrontMatter, seenMetadata := false, false, false
+	// Lines in fenced code blocks of the body are never metadata
+	inCodeBlock := false
+
+	for i, rawLine := range lines {
+		line := strings.TrimSpace(rawLine)
 		matches := ticketRegex.FindStringSubmatch(line)
 
 		if len(matches) > 0 {
@@ -547,9 +2404,47 @@ func parseTickets(path string) ([]Ticket, error) {
 				Number:      ticketNum,
 				Description: desc,
 			})
+			bodies = append(bodies, []string{})
+			inMetadata, inFrontMatter, seenMetadata = true, false, false
+			inCodeBlock = false
+		} else if len(tickets) > 0 {
+			ticket := &tickets[len(tickets)-1]
+			if inMetadata {
+				switch {
+				case inFrontMatter && line == "---":
+					inMetadata = false
+					continue
+				case inFrontMatter:
+					if matches := metadataRegex.FindStringSubmatch(line); matches != nil {
+						ticket.setMetadata(matches[1], matches[2])
+					}
+					continue
+				case line == "---" && !seenMetadata && isFrontMatter(lines[i+1:], metadataRegex):
+					// Otherwise it is a horizontal rule in the body
+					inFrontMatter = true
+					continue
+				case line == "" && !seenMetadata:
+					continue
+				}
+				if matches := metadataRegex.FindStringSubmatch(line); matches != nil && ticket.setMetadata(matches[1], matches[2]) {
+					seenMetadata = true
+					continue
+				}
+				inMetadata = false
+			}
+			if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+				inCodeBlock = !inCodeBlock
+			} else if matches := metadataRegex.FindStringSubmatch(line); matches != nil && !inCodeBlock && bodyMetadataKeys[strings.ToLower(matches[1])] {
+				ticket.setMetadata(matches[1], matches[2])
+			}
+			bodies[len(bodies)-1] = append(bodies[len(bodies)-1], strings.TrimRight(rawLine, " \t\r"))
 		}
 	}
 
+	for i := range tickets {
+		tickets[i].Body = strings.Trim(strings.Join(bodies[i], "\n"), "\n")
+	}
+
 	// Sort tickets by number (in case they were out of order)
 	// Simple bubble sort for small lists
 	for i := 0; i < len(tickets); i++ {
@@ -563,39 +2458,744 @@ func parseTickets(path string) ([]Ticket, error) {
 	return tickets, nil
 }
 
-func (m Model) runNextAgent() tea.Cmd {
+// isFrontMatter reports whether the lines after a "---" line are front
+// matter: known "Key: value" lines or blank lines up to a closing "---".
+func isFrontMatter(lines []string, metadataRegex *regexp.Regexp) bool {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "---" {
+			return true
+		}
+		if line == "" {
+			continue
+		}
+		matches := metadataRegex.FindStringSubmatch(line)
+		if matches == nil || !(&Ticket{}).setMetadata(matches[1], matches[2]) {
+			return false
+		}
+	}
+	return false
+}
+
+// bodyMetadataKeys are recognized anywhere in the ticket body, not just in the
+// metadata block, as tickets written before the block existed rely on it.
+// Their lines stay part of the body. Lines in code blocks and values that do
+// not parse completely are ignored, so prose and samples set nothing.
+var bodyMetadataKeys = map[string]bool{"depends": true, "depends on": true, "timeout": true}
+
+// setMetadata applies a "Key: value" line from the ticket's metadata block.
+// It reports whether the key is known. Invalid values are ignored.
+func (t *Ticket) setMetadata(key, value string) bool {
+	// Front matter may write lists YAML style: [a, b]
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		value = value[1 : len(value)-1]
+	}
+	value = strings.Trim(value, `"'`)
+
+	switch strings.ToLower(key) {
+	case "timeout":
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			t.Timeout = timeout
+		}
+	case "depends", "depends on":
+		if deps, ok := parseDependencies(value, t.Number); ok {
+			t.Dependencies = deps
+		}
+	case "agent", "profile":
+		t.Agent = value
+	case "dir", "workdir":
+		t.Dir = filepath.Clean(value)
+	case "labels":
+		t.Labels = nil
+		for _, label := range strings.Split(value, ",") {
+			if label = strings.Trim(strings.TrimSpace(label), `"'`); label != "" {
+				t.Labels = append(t.Labels, label)
+			}
+		}
+	case "verify":
+		if value != "" {
+			t.Verify = append(t.Verify, value)
+		}
+	case "retries":
+		if retries, err := strconv.Atoi(value); err == nil && retries >= 0 {
+			t.Retries = &retries
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// parseDependencies reads a list of ticket numbers such as "1, 3" or "#2 #4".
+// It reports false if anything else is in the list. A ticket cannot depend on
+// itself.
+func parseDependencies(list string, self int) ([]int, bool) {
+	deps := []int{}
+	for _, field := range strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		dep, err := strconv.Atoi(strings.TrimPrefix(field, "#"))
+		if err != nil || dep <= 0 {
+			return nil, false
+		}
+		if dep != self {
+			deps = append(deps, dep)
+		}
+	}
+	return deps, true
+}
+
+// runAgent starts the agent for the ticket at the given index.
+func (m Model) runAgent(index int) tea.Cmd {
+	// Copy what the command needs, it runs outside the Update loop
+	ticket := m.Tickets[index]
+	var previous Ticket
+	if index > 0 {
+		previous = m.Tickets[index-1]
+	}
+	agent, agentErr := m.ticketAgent(ticket)
+	bases := m.dependencyBranches(ticket)
 	return func() tea.Msg {
 		standardPrompt, err := os.ReadFile(m.StandardPromptPath)
 		if err != nil {
-			return tickMsg{output: "", err: err}
+			return tickMsg{ticket: index, err: err}
 		}
 
-		// Add kill file instruction to the prompt
-		prompt := fmt.Sprintf("%s Please use the documentation in the input/%s folder, especially the specification.md and the tickets.md. Please work on ticket %d. As your final task, create a file named 'killmenow.md' containing either 'success' or 'failure' to indicate whether you successfully completed the task.",
-			string(standardPrompt), m.SelectedProject, m.CurrentTicket+1)
+		if m.UseWorktrees {
+			ticket.Branch, ticket.WorktreePath, err = createWorktree(m.RunDir, ticket, bases)
+			if err != nil {
+				return tickMsg{ticket: index, err: err}
+			}
+		}
 
-		cmdParts := strings.Fields(m.CustomAgentCommand)
-		if len(cmdParts) == 0 {
-			return tickMsg{output: "", err: fmt.Errorf("invalid command")}
+		prompt, err := m.buildPrompt(string(standardPrompt), ticket, previous)
+		if err != nil {
+			return tickMsg{ticket: index, err: err}
 		}
 
-		// Append prompt as a command-line argument
-		args := append(cmdParts[1:], prompt)
-		cmd := exec.Command(cmdParts[0], args...)
+		if agentErr != nil {
+			return tickMsg{ticket: index, err: agentErr}
+		}
+		if agent.Binary == "" {
+			return tickMsg{ticket: index, err: fmt.Errorf("invalid command")}
+		}
+		// A relative agent path must keep working from another directory
+		binary := agent.Binary
+		dir := m.agentDir(ticket)
+		if dir != "" && strings.ContainsRune(binary, filepath.Separator) {
+			binary = absPath(binary)
+		}
+
+		args := append([]string{}, agent.Args...)
+		env := []string{killFileEnv + "=" + m.killFilePath(ticket)}
+		for key, value := range agent.Env {
+			env = append(env, key+"="+value)
+		}
+
+		// Hand the prompt over as configured for the agent
+		var stdin io.Reader
+		promptFile := ""
+		switch agent.Prompt {
+		case PromptStdin:
+			stdin = strings.NewReader(prompt)
+		case PromptFile:
+			promptFile, err = writePromptFile(prompt)
+			if err != nil {
+				return tickMsg{ticket: index, err: err}
+			}
+			for i, arg := range args {
+				args[i] = strings.ReplaceAll(arg, promptFilePlaceholder, promptFile)
+			}
+			env = append(env, promptFileEnv+"="+promptFile)
+		default:
+			args = append(args, prompt)
+		}
+
+		cmd := exec.Command(binary, args...)
+		cmd.Dir = dir
+		cmd.Stdin = stdin
+		cmd.Env = append(os.Environ(), env...)
+		setProcessGroup(cmd)
+
+		// Stream stdout and stderr into the ticket's log file
+		logFile, logPath, err := m.createTicketLog(ticket)
+		if err != nil {
+			removeFile(promptFile)
+			return tickMsg{ticket: index, err: err}
+		}
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
 
 		// Start the command asynchronously
-		if err := cmd.Start(); err != nil {
-			return tickMsg{output: "", err: err}
+		err = cmd.Start()
+		// The child has its own copy of the descriptor, ours is no longer needed
+		_ = logFile.Close()
+		if err != nil {
+			removeFile(promptFile)
+			return tickMsg{ticket: index, err: err}
 		}
 
 		// Return a message indicating the process has started
-		return processStartedMsg{cmd: cmd}
+		return processStartedMsg{
+			ticket:       index,
+			cmd:          cmd,
+			logPath:      logPath,
+			promptFile:   promptFile,
+			branch:       ticket.Branch,
+			worktreePath: ticket.WorktreePath,
+		}
+	}
+}
+
+// writePromptFile stores the prompt in a temporary file for agents that
+// read it from disk and returns the file's path.
+func writePromptFile(prompt string) (string, error) {
+	file, err := os.CreateTemp("", "project-manager-prompt-*.md")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.WriteString(prompt); err != nil {
+		_ = file.Close()
+		removeFile(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		removeFile(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+func removeFile(path string) {
+	if path != "" {
+		_ = os.Remove(path)
+	}
+}
+
+// promptData is available to standard-prompt.md when it is a template.
+type promptData struct {
+	Project           string
+	TicketNumber      int
+	Description       string
+	Body              string
+	Labels            []string
+	SpecificationPath string
+	TicketsPath       string
+	KillFile          string
+	PreviousTicket    int    // 0 for the first ticket
+	PreviousResult    string // success, failure, unknown, skipped or empty if unfinished
+	Instructions      string // The default wording about the ticket and kill file
+	Attempt           int    // 1 for the first attempt
+	RetryContext      string // Why the previous attempt failed, empty on the first
+}
+
+// buildPrompt combines the standard prompt with the ticket to work on and the
+// kill file instruction. The ticket is referenced by its number from
+// tickets.md, which is not necessarily its position in the queue. A standard
+// prompt containing template actions is rendered instead and replaces the
+// default wording entirely.
+func (m Model) buildPrompt(standardPrompt string, ticket, previous Ticket) (string, error) {
+	data := m.promptData(ticket, previous)
+	if strings.Contains(standardPrompt, "{{") {
+		return renderPromptTemplate(m.StandardPromptPath, standardPrompt, data)
+	}
+
+	prompt := standardPrompt + " " + data.Instructions
+	if ticket.Body != "" {
+		prompt += "\n\nTicket details:\n" + ticket.Body
+	}
+	if data.RetryContext != "" {
+		prompt += "\n\n" + data.RetryContext
+	}
+	return prompt, nil
+}
+
+func (m Model) promptData(ticket, previous Ticket) promptData {
+	workOn := fmt.Sprintf("ticket %d", ticket.Number)
+	if ticket.Description != "" {
+		workOn += ": " + ticket.Description
+	}
+
+	// Agents in another directory need absolute paths back to the documentation
+	docsDir := "input/" + m.SelectedProject
+	specPath, ticketsPath := m.SpecificationPath, m.TicketsPath
+	if m.agentDir(ticket) != "" {
+		docsDir = absPath(docsDir)
+		specPath = absPath(specPath)
+		ticketsPath = absPath(ticketsPath)
+	}
+	killFile := m.killFilePath(ticket)
+
+	return promptData{
+		Project:           m.SelectedProject,
+		TicketNumber:      ticket.Number,
+		Description:       ticket.Description,
+		Body:              ticket.Body,
+		Labels:            ticket.Labels,
+		SpecificationPath: specPath,
+		TicketsPath:       ticketsPath,
+		KillFile:          killFile,
+		PreviousTicket:    previous.Number,
+		PreviousResult:    previous.result(),
+		Instructions: fmt.Sprintf("Please use the documentation in the %s folder, especially the specification.md and the tickets.md. Please work on %s. As your final task, create a file named '%s' containing either 'success' or 'failure' to indicate whether you successfully completed the task. Instead of the plain word you may write a JSON report such as {\"status\": \"success\", \"summary\": \"what you did\", \"files_changed\": [\"path\"], \"follow_ups\": [\"work left for later\"]}, where the status is one of success, failure, partial or blocked. To propose new tickets for work you discovered, add \"new_tickets\": [{\"description\": \"title\", \"body\": \"details\"}] to the report.",
+			docsDir, workOn, killFile),
+		Attempt:      ticket.Attempts,
+		RetryContext: m.retryContext(ticket),
+	}
+}
+
+// retryLogTailBytes and retryVerifyTailBytes limit how much of the previous
+// attempt's agent and verification output is passed on to the next one.
+const (
+	retryLogTailBytes    = 4000
+	retryVerifyTailBytes = 4000
+)
+
+// retryContext tells the agent why the ticket's previous attempt failed,
+// including the end of its verification output and of its agent log.
+func (m Model) retryContext(ticket Ticket) string {
+	if len(ticket.History) == 0 {
+		return ""
+	}
+	last := ticket.History[len(ticket.History)-1]
+	if !last.Status.failed() {
+		return ""
+	}
+	context := fmt.Sprintf("This is attempt %d of %d. The previous attempt failed: %s.",
+		ticket.countedAttempts(), m.ticketRetries(ticket)+1, last.FailureReason)
+	if tail := logTail(last.VerifyLogPath, retryVerifyTailBytes); tail != "" {
+		context += fmt.Sprintf("\n\nThe end of its verification output was:\n\n%s", tail)
+	}
+	if tail := logTail(last.LogPath, retryLogTailBytes); tail != "" {
+		context += fmt.Sprintf("\n\nThe end of its agent output was:\n\n%s", tail)
+	}
+	return context
+}
+
+// logTail returns at most the last maxBytes of a log file, starting at a line
+// boundary. It returns an empty string if the file cannot be read.
+func logTail(path string, maxBytes int) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	if len(data) > maxBytes {
+		data = data[len(data)-maxBytes:]
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// defaultRateLimitPatterns match the rate limit and overload messages of
+// common agents.
+var defaultRateLimitPatterns = []string{
+	`(?i)rate.?limit`,
+	`(?i)too many requests`,
+	`(?i)overload`,
+	`(?i)usage limit`,
+	`(?i)quota exceeded`,
+}
+
+// rateLimitTailBytes limits the scan for rate limit messages to the end of
+// the agent's output, where the error that stopped it is printed. Earlier
+// output may well mention rate limits as part of the ticket's work.
+const rateLimitTailBytes = 2000
+
+// maxRateLimitRetries is how often a ticket is started again after rate
+// limits before it counts as failed.
+const maxRateLimitRetries = 10
+
+// detectRateLimit checks whether the ticket's agent stopped because of a rate
+// limit and how long to wait before starting it again: as long as the output
+// asks for, or with exponential backoff otherwise.
+func (m Model) detectRateLimit(ticket Ticket, exitCode int) (string, time.Duration, bool) {
+	profile, err := m.ticketAgent(ticket)
+	if err != nil {
+		return "", 0, false
+	}
+	output := logTail(ticket.LogPath, rateLimitTailBytes)
+	if !profile.rateLimited(output, exitCode) {
+		return "", 0, false
+	}
+	if wait, ok := parseRateLimitWait(output, time.Now()); ok {
+		return fmt.Sprintf("rate limited for %s", formatDuration(wait)), wait, true
+	}
+	return "rate limited", rateLimitBackoff(m.RateLimitHits + 1), true
+}
+
+// rateLimitBackoff returns the wait after the given number of rate limits in a
+// row: 30 seconds, doubling up to 10 minutes.
+func rateLimitBackoff(hits int) time.Duration {
+	wait := 30 * time.Second
+	for i := 1; i < hits && wait < 10*time.Minute; i++ {
+		wait *= 2
+	}
+	if wait > 10*time.Minute {
+		wait = 10 * time.Minute
+	}
+	return wait
+}
+
+var (
+	retryAfterPattern = regexp.MustCompile(`(?i)retry-after:?\s*(\d+)`)
+	retryInPattern    = regexp.MustCompile(`(?i)(?:try again|retry|resets?) in (\d+)\s*(hours?|h|minutes?|mins?|m|seconds?|secs?|s)\b`)
+	resetUnixPattern  = regexp.MustCompile(`(?i)(?:resets?(?: at)?:?\s*|limit reached\|)(\d{10})\b`)
+	resetTimePattern  = regexp.MustCompile(`(?i)resets? at:?\s*(\d{4}-\d{2}-\d{2}T\S+)`)
+)
+
+// parseRateLimitWait looks for a hint in the agent's output on when to try
+// again: a Retry-After header in seconds, "try again in 5 minutes", or a reset
+// time as Unix timestamp or RFC 3339. The last hint in the output wins, as it
+// belongs to the error that stopped the agent.
+func parseRateLimitWait(output string, now time.Time) (time.Duration, bool) {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if match := retryAfterPattern.FindStringSubmatch(line); match != nil {
+			seconds, _ := strconv.Atoi(match[1])
+			return time.Duration(seconds) * time.Second, seconds > 0
+		}
+		if match := retryInPattern.FindStringSubmatch(line); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			unit := time.Second
+			switch strings.ToLower(match[2])[0] {
+			case 'h':
+				unit = time.Hour
+			case 'm':
+				unit = time.Minute
+			}
+			return time.Duration(n) * unit, n > 0
+		}
+		if match := resetUnixPattern.FindStringSubmatch(line); match != nil {
+			seconds, _ := strconv.ParseInt(match[1], 10, 64)
+			wait := time.Unix(seconds, 0).Sub(now)
+			return wait, wait > 0
+		}
+		if match := resetTimePattern.FindStringSubmatch(line); match != nil {
+			reset, err := time.Parse(time.RFC3339, strings.TrimRight(match[1], ".,;)"))
+			if err == nil {
+				wait := reset.Sub(now)
+				return wait, wait > 0
+			}
+		}
+	}
+	return 0, false
+}
+
+// renderPromptTemplate executes standard-prompt.md as a text/template.
+func renderPromptTemplate(path, text string, data promptData) (string, error) {
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", err
+	}
+	return prompt.String(), nil
+}
+
+// absPath returns the absolute form of path, or path itself if that fails.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// killFileEnv passes the kill file's path to the agent.
+const killFileEnv = "KILL_FILE"
+
+// killFilePath returns the kill file the agent working on a ticket must
+// write. Every attempt gets its own absolute path in the run directory, so
+// neither parallel agents nor files left over from a crash can mix up
+// results.
+func (m Model) killFilePath(ticket Ticket) string {
+	return filepath.Join(absPath(m.RunDir), fmt.Sprintf("killmenow-%d-%d.md", ticket.Number, ticket.Attempts))
+}
+
+// findStaleKillFiles lists kill files that exist although no agent is running:
+// leftovers in the run directory of a resumed run, and files in the current
+// directory written for older versions of this tool.
+func findStaleKillFiles(runDir string) []string {
+	stale, _ := filepath.Glob("killmenow*.md")
+	if runDir != "" {
+		inRunDir, _ := filepath.Glob(filepath.Join(runDir, "killmenow-*.md"))
+		stale = append(stale, inRunDir...)
+	}
+	return stale
+}
+
+// agentDir returns the directory the agent for a ticket runs in: the
+// profile's directory inside the ticket's worktree or the current checkout.
+// It is empty for the current directory.
+func (m Model) agentDir(ticket Ticket) string {
+	dir := ticket.Dir
+	if dir == "" {
+		agent, _ := m.ticketAgent(ticket)
+		dir = agent.Dir
+	}
+	return filepath.Join(ticket.WorktreePath, dir)
+}
+
+// ticketRetries returns how often a failed ticket is started again. A
+// ticket's own value wins even if it is 0.
+func (m Model) ticketRetries(ticket Ticket) int {
+	if ticket.Retries != nil {
+		return *ticket.Retries
+	}
+	return m.Retries
+}
+
+// ticketAgent returns the agent profile a ticket runs with: the one named in
+// its metadata, or the run's agent.
+func (m Model) ticketAgent(ticket Ticket) (AgentProfile, error) {
+	if ticket.Agent == "" {
+		return m.Agent, nil
+	}
+	if profile, ok := findProfile(m.Profiles, ticket.Agent); ok {
+		return profile, nil
+	}
+	return m.Agent, fmt.Errorf("unknown agent profile %q", ticket.Agent)
+}
+
+// commitMu serializes commits, which would otherwise race on the
+// repository's index and refs.
+var commitMu sync.Mutex
+
+// commitTicket stages and commits all changes in the ticket's working
+// directory, leaving out the run logs and run state. It returns an empty
+// sha if nothing changed.
+func commitTicket(ticket Ticket) (string, string, error) {
+	commitMu.Lock()
+	defer commitMu.Unlock()
+
+	git := func(args ...string) (string, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = ticket.WorktreePath
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	if _, err := git("add", "-A", "--", ".", ":(exclude)runs", ":(exclude)input/*/"+runStateFile); err != nil {
+		return "", "", err
+	}
+	// git diff --quiet exits with 1 when there are staged changes
+	diff := exec.Command("git", "diff", "--cached", "--quiet")
+	diff.Dir = ticket.WorktreePath
+	if diff.Run() == nil {
+		return "", "", nil
+	}
+
+	message := fmt.Sprintf("Ticket %d: %s", ticket.Number, ticket.Description)
+	if _, err := git("commit", "-q", "-m", message); err != nil {
+		return "", "", err
+	}
+	sha, err := git("rev-parse", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	diffStat, err := git("show", "--stat", "--format=", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	return sha, diffStat, nil
+}
+
+// diffStatSummary returns the last line of a diffstat, e.g.
+// "2 files changed, 10 insertions(+)".
+func diffStatSummary(diffStat string) string {
+	lines := strings.Split(diffStat, "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// shortSHA abbreviates a commit hash for display.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// worktreeBranch names the branch of a ticket's worktree after its number
+// and description, e.g. "ticket-3-add-login-page".
+func worktreeBranch(ticket Ticket) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, ticket.Description)
+	slug = regexp.MustCompile(`-+`).ReplaceAllString(slug, "-")
+	if len(slug) > 40 {
+		slug = slug[:40]
+	}
+	slug = strings.Trim(slug, "-")
+
+	branch := fmt.Sprintf("ticket-%d", ticket.Number)
+	if slug != "" {
+		branch += "-" + slug
+	}
+	return branch
+}
+
+// commitSettingsError reports why the run's commit setting is unsafe. Agents
+// sharing the main checkout would put each other's changes into their
+// commits, so parallel agents can only commit in their own worktrees.
+func (m Model) commitSettingsError() error {
+	if m.AutoCommit && m.Concurrency > 1 && !m.UseWorktrees {
+		return errors.New("committing with more than one parallel agent needs worktrees")
+	}
+	return nil
+}
+
+// dependencyBranches returns the worktree branches of a ticket's
+// dependencies, so that its own branch can start from their work.
+func (m Model) dependencyBranches(ticket Ticket) []string {
+	branches := []string{}
+	for _, dep := range ticket.Dependencies {
+		if j := m.ticketIndex(dep); j >= 0 && m.Tickets[j].Branch != "" {
+			branches = append(branches, m.Tickets[j].Branch)
+		}
+	}
+	return branches
+}
+
+// worktreeWarning explains why dependent tickets will not see each other's
+// work, or returns "" if they will.
+func (m Model) worktreeWarning() string {
+	if !m.UseWorktrees || m.AutoCommit {
+		return ""
+	}
+	for _, ticket := range m.Tickets {
+		if len(ticket.Dependencies) > 0 {
+			return "Without commits, tickets in worktrees do not see the work of the tickets they depend on"
+		}
+	}
+	return ""
+}
+
+// createWorktree checks out a new branch for the ticket into its own git
+// worktree below the run directory. The branch starts from the first of
+// bases, the branches of the ticket's dependencies, and the others are merged
+// into it. Without bases it starts from the current HEAD. Resumed runs reuse
+// an existing worktree or branch. It returns the branch name and the
+// worktree's absolute path.
+func createWorktree(runDir string, ticket Ticket, bases []string) (string, string, error) {
+	branch := worktreeBranch(ticket)
+	path, err := filepath.Abs(filepath.Join(runDir, "worktrees", fmt.Sprintf("ticket-%d", ticket.Number)))
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return branch, path, nil
+	}
+
+	if exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Run() == nil {
+		if out, err := exec.Command("git", "worktree", "add", path, branch).CombinedOutput(); err != nil {
+			return "", "", fmt.Errorf("creating worktree: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return branch, path, nil
+	}
+
+	args := []string{"worktree", "add", "-b", branch, path}
+	if len(bases) > 0 {
+		args = append(args, bases[0])
+	}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return "", "", fmt.Errorf("creating worktree: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	for _, base := range bases[min(1, len(bases)):] {
+		merge := exec.Command("git", "merge", "-q", "--no-edit", base)
+		merge.Dir = path
+		if out, err := merge.CombinedOutput(); err != nil {
+			return "", "", fmt.Errorf("merging %s into worktree: %v: %s", base, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return branch, path, nil
+}
+
+// ticketTimeout returns the timeout for a ticket, preferring its own override.
+func (m Model) ticketTimeout(ticket Ticket) time.Duration {
+	if ticket.Timeout > 0 {
+		return ticket.Timeout
+	}
+	return m.Timeout
+}
+
+// timeoutPresets are the run timeouts offered on the confirmation screen.
+var timeoutPresets = []time.Duration{0, 10 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour}
+
+func nextTimeout(current time.Duration) time.Duration {
+	for i, preset := range timeoutPresets {
+		if preset == current {
+			return timeoutPresets[(i+1)%len(timeoutPresets)]
+		}
+	}
+	return timeoutPresets[0]
+}
+
+func formatTimeout(d time.Duration) string {
+	if d == 0 {
+		return "none"
+	}
+	return d.String()
+}
+
+// attemptSuffix keeps the logs of a retried ticket apart. The first attempt
+// uses the plain name, so runs without retries look as before.
+func attemptSuffix(ticket Ticket) string {
+	if ticket.Attempts <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("-attempt-%d", ticket.Attempts)
+}
+
+// createTicketLog creates the log file for a ticket inside the run directory.
+func (m Model) createTicketLog(ticket Ticket) (*os.File, string, error) {
+	if err := os.MkdirAll(m.RunDir, 0o755); err != nil {
+		return nil, "", fmt.Errorf("creating run directory: %w", err)
+	}
+	logPath := filepath.Join(m.RunDir, fmt.Sprintf("ticket-%d%s.log", ticket.Number, attemptSuffix(ticket)))
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating log file: %w", err)
+	}
+	return logFile, logPath, nil
+}
+
+func checkForKillFile(ticket int, cmd *exec.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		return checkKillFileMsg{ticket: ticket, cmd: cmd}
 	}
 }
 
-func checkForKillFile() tea.Cmd {
+// waitForExit blocks until the agent process terminates, reaps it and
+// reports its exit status.
+func waitForExit(ticket int, cmd *exec.Cmd) tea.Cmd {
 	return func() tea.Msg {
-		return checkKillFileMsg{}
+		err := cmd.Wait()
+		if err == nil {
+			return processExitedMsg{ticket: ticket, cmd: cmd}
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return processExitedMsg{ticket: ticket, cmd: cmd, exitCode: exitErr.ExitCode()}
+		}
+		return processExitedMsg{ticket: ticket, cmd: cmd, exitCode: -1, err: err}
 	}
 }
 
@@ -634,13 +3234,20 @@ func (m Model) View() string {
 			s += infoStyle.Render("Please create a project folder with specification.md, tickets.md, and standard-prompt.md files")
 		} else {
 			for i, project := range m.AvailableProjects {
+				label := project
+				if state, ok := m.SavedRuns[project]; ok {
+					label += fmt.Sprintf(" (unfinished run: %d/%d tickets done)", state.finishedCount(), len(state.Tickets))
+				}
 				if i == m.SelectedProjectIndex {
-					s += selectedStyle.Render("→ "+project) + "\n"
+					s += selectedStyle.Render("→ "+label) + "\n"
 				} else {
-					s += "  " + project + "\n"
+					s += "  " + label + "\n"
 				}
 			}
 			s += "\n" + infoStyle.Render("Use ↑/↓ to navigate, Enter to select")
+			if len(m.SavedRuns) > 0 {
+				s += "\n" + infoStyle.Render("Press r to resume an unfinished run")
+			}
 		}
 
 	case StateFileCheck:
@@ -650,21 +3257,71 @@ func (m Model) View() string {
 		s += "Checking for required files...\n\n"
 		s += successStyle.Render("✅ Successfully found specification.md") + "\n"
 		s += successStyle.Render(fmt.Sprintf("✅ Successfully found tickets.md (%d tickets)", len(m.Tickets))) + "\n"
-		s += successStyle.Render("✅ Successfully found standard-prompt.md") + "\n\n"
-		s += infoStyle.Render("All files found! Press any key to continue...")
+		if m.PromptError != nil {
+			s += errorStyle.Render("❌ Invalid template in standard-prompt.md: "+m.PromptError.Error()) + "\n\n"
+			s += infoStyle.Render("Fix the template and press any key to check again...")
+			break
+		}
+		s += successStyle.Render("✅ Successfully found standard-prompt.md") + "\n"
+		if m.VerifyError != nil {
+			s += errorStyle.Render("❌ Cannot read verify.txt: "+m.VerifyError.Error()) + "\n\n"
+			s += infoStyle.Render("Fix the file and press any key to check again...")
+			break
+		}
+		if len(m.VerifyCommands) > 0 {
+			s += successStyle.Render(fmt.Sprintf("✅ Successfully found verify.txt (%d command%s)", len(m.VerifyCommands), plural(len(m.VerifyCommands)))) + "\n"
+		}
+		s += "\n" + infoStyle.Render("All files found! Press any key to continue...")
 
 	case StateFilePicker:
 		s += fmt.Sprintf("Missing file: %s\n", errorStyle.Render(m.MissingFiles[m.CurrentMissingIndex]))
 		s += "Please select the file location:\n\n"
 		s += m.FilePicker.View()
 
+	case StateTicketSelection:
+		s += fmt.Sprintf("Select tickets to run (%d of %d selected):\n\n", len(m.selectedTickets()), len(m.Tickets))
+
+		previous := map[int]TicketStatus{}
+		if m.PreviousRun != nil {
+			for _, ticket := range m.PreviousRun.Tickets {
+				previous[ticket.Number] = ticket.Status
+			}
+		}
+		for i, ticket := range m.Tickets {
+			check := "[ ]"
+			if m.Selected[ticket.Number] {
+				check = "[x]"
+			}
+			line := fmt.Sprintf("%s Ticket %d: %s", check, ticket.Number, ticket.Description)
+			if status, ok := previous[ticket.Number]; ok && status != StatusPending {
+				line += fmt.Sprintf(" (last run: %s %s)", status.icon(), strings.ToLower(status.label()))
+			}
+			if i == m.SelectionAnchor {
+				line += " ◀ range start"
+			}
+			if i == m.SelectionCursor {
+				s += selectedStyle.Render("→ "+line) + "\n"
+			} else {
+				s += "  " + line + "\n"
+			}
+		}
+
+		if m.OnlyFailed {
+			s += "\n" + infoStyle.Render("Only tickets that failed in the previous run are selected") + "\n"
+		}
+		if len(m.selectedTickets()) == 0 {
+			s += "\n" + errorStyle.Render("Select at least one ticket to continue") + "\n"
+		}
+		s += "\n" + infoStyle.Render("Press space to toggle, v twice to select a range, A to select all or none, f to select only previously failed tickets, shift+↑/↓ or K/J to move a ticket, Enter to continue")
+
 	case StateAgentSelection:
 		s += "Select coding agent:\n\n"
 
-		choices := []string{
-			"claude --dangerously-skip-permissions",
-			"Other (enter custom command)",
+		choices := []string{}
+		for _, profile := range m.Profiles {
+			choices = append(choices, fmt.Sprintf("%s (%s)", profile.Name, profile))
 		}
+		choices = append(choices, "Other (enter custom command)")
 
 		for i, choice := range choices {
 			if i == m.SelectedAgent {
@@ -674,11 +3331,17 @@ func (m Model) View() string {
 			}
 		}
 
+		if m.AgentError != nil {
+			s += "\n" + errorStyle.Render("⚠️  "+m.AgentError.Error()) + "\n"
+		}
 		s += "\n" + infoStyle.Render("Press Enter to continue")
 
 	case StateCustomCommandEntry:
 		s += "Enter custom agent command:\n\n"
 		s += m.TextInput.View() + "\n\n"
+		if m.AgentError != nil {
+			s += errorStyle.Render("⚠️  "+m.AgentError.Error()) + "\n\n"
+		}
 		s += infoStyle.Render("Press Enter when done")
 
 	case StateConfirmation:
@@ -687,95 +3350,850 @@ func (m Model) View() string {
 		s += fmt.Sprintf("📁 Specification: %s\n", m.SpecificationPath)
 		s += fmt.Sprintf("📋 Tickets: %s (%d tickets)\n", m.TicketsPath, len(m.Tickets))
 		s += fmt.Sprintf("📝 Prompt: %s\n", m.StandardPromptPath)
-		s += fmt.Sprintf("🤖 Agent: %s\n", m.CustomAgentCommand)
+		s += fmt.Sprintf("🤖 Agent: %s (%s)\n", m.Agent.Name, m.Agent)
+		s += fmt.Sprintf("📨 Prompt delivery: %s\n", m.Agent.Prompt)
 		s += fmt.Sprintf("⏱️  Delay between agents: %d seconds\n", m.DelaySeconds)
+		s += fmt.Sprintf("🚪 Clean exit without kill file: %s\n", m.ExitPolicy)
+		s += fmt.Sprintf("⌛ Timeout per ticket: %s\n", formatTimeout(m.Timeout))
+		s += fmt.Sprintf("🔀 Parallel agents: %d\n", m.Concurrency)
+		s += fmt.Sprintf("🔁 Retries for failed tickets: %d\n", m.Retries)
+		s += fmt.Sprintf("🌳 Git worktree per ticket: %s\n", onOff(m.UseWorktrees))
+		s += fmt.Sprintf("📦 Commit after each ticket: %s\n", onOff(m.AutoCommit))
+		s += fmt.Sprintf("📝 Write approved follow-up tickets to tickets.md: %s\n", onOff(m.WriteBackTickets))
+		if m.ResumeState != nil {
+			if next := nextUnfinishedTicket(m.Tickets, 0); next < len(m.Tickets) {
+				s += fmt.Sprintf("🔁 Resuming from ticket %d (%d already finished)\n", m.Tickets[next].Number, next)
+			}
+		}
+		if m.commitSettingsError() != nil {
+			s += "\n" + errorStyle.Render("❌ Committing with more than one parallel agent needs worktrees. Press w, a or c to change it") + "\n"
+		}
+		if warning := m.worktreeWarning(); warning != "" {
+			s += "\n" + errorStyle.Render("⚠️  "+warning) + "\n"
+		}
+		if warning := m.resumeAgentWarning(); warning != "" {
+			s += "\n" + errorStyle.Render("⚠️  "+warning) + "\n"
+		}
+		if len(m.StaleKillFiles) > 0 {
+			s += "\n" + errorStyle.Render("⚠️  Stale kill files will be removed: "+strings.Join(m.StaleKillFiles, ", ")) + "\n"
+		}
 
 		s += "\n" + successStyle.Render("Press Enter to start")
+		s += "\n" + infoStyle.Render("Press e to change the clean exit policy, t to change the timeout, c to change the parallel agents, r to change the retries, w to toggle worktrees, a to toggle commits, p to change the prompt delivery, f to toggle writing follow-ups")
 
 	case StateRunning:
-		s += fmt.Sprintf("Executing agents... (Ticket %d/%d)\n\n", m.CurrentTicket+1, len(m.Tickets))
+		finished := 0
+		for _, ticket := range m.Tickets {
+			if ticket.finished() {
+				finished++
+			}
+		}
+		s += fmt.Sprintf("Executing agents... (%d/%d finished, %d running)\n\n", finished, len(m.Tickets), len(m.Running))
+		if m.IsWaiting && m.WaitReason != "" {
+			remaining := time.Until(m.WaitingUntil)
+			if remaining < 0 {
+				remaining = 0
+			}
+			s += errorStyle.Render(fmt.Sprintf("⏳ %s, resuming in %s", m.WaitReason, formatDuration(remaining))) + "\n\n"
+		}
+		if m.Paused {
+			s += errorStyle.Render("⏸  Paused: running agents finish, no new tickets start") + "\n\n"
+		}
+
+		// The countdown is shown on the ticket that starts next
+		nextReady := -1
+		for i := range m.Tickets {
+			if m.ticketReady(i) {
+				nextReady = i
+				break
+			}
+		}
 
 		// Show ticket status with emojis
+		lines := []string{}
 		for i, ticket := range m.Tickets {
 			var status string
 			var timeInfo string
+			agent, running := m.Running[i]
 
-			if i < m.CurrentTicket {
-				// Completed tickets - show duration
-				if ticket.Failed {
-					status = "❌"
-				} else {
-					status = "✅"
-				}
+			if ticket.finished() {
+				// Finished tickets - show duration
+				status = ticket.Status.icon()
 				duration := ticket.EndTime.Sub(ticket.StartTime)
 				timeInfo = fmt.Sprintf(" - %s", formatDuration(duration))
-			} else if i == m.CurrentTicket {
-				// Current ticket
-				if m.ProcessRunning {
-					status = "🔄"
-					// Show live duration for running ticket
-					if !ticket.StartTime.IsZero() {
-						currentDuration := time.Since(ticket.StartTime)
-						timeInfo = fmt.Sprintf(" - %s", formatDuration(currentDuration))
-					}
-				} else if m.IsWaiting {
-					remainingTime := int(time.Until(m.WaitingUntil).Seconds())
-					if remainingTime < 0 {
-						remainingTime = 0
-					}
-					status = fmt.Sprintf("⏳ (%ds)", remainingTime)
-				} else {
-					status = "⏸️"
+				if ticket.FailureReason != "" {
+					timeInfo += fmt.Sprintf(" (%s)", ticket.FailureReason)
+				}
+			} else if running {
+				status = StatusRunning.icon()
+				if agent.Terminating {
+					status = "🛑"
+				} else if agent.Verifying {
+					status = "🔍"
+				} else if agent.Committing {
+					status = "📦"
+				}
+				// Show live duration for running ticket
+				if !ticket.StartTime.IsZero() {
+					currentDuration := time.Since(ticket.StartTime)
+					timeInfo = fmt.Sprintf(" - %s", formatDuration(currentDuration))
+				}
+				if agent.Terminating {
+					timeInfo += fmt.Sprintf(" (%s, terminating)", ticket.FailureReason)
+				} else if retries := m.ticketRetries(ticket); retries > 0 {
+					timeInfo += fmt.Sprintf(" (attempt %d of %d)", ticket.countedAttempts(), retries+1)
+				}
+			} else if m.IsWaiting && i == nextReady {
+				remainingTime := int(time.Until(m.WaitingUntil).Seconds())
+				if remainingTime < 0 {
+					remainingTime = 0
+				}
+				status = fmt.Sprintf("%s (%ds)", ticket.Status.icon(), remainingTime)
+				if ticket.Attempts > 0 {
+					timeInfo = fmt.Sprintf(" (retrying after: %s)", ticket.FailureReason)
 				}
 			} else {
-				status = "⏳"
+				status = ticket.Status.icon()
+				if m.Paused {
+					status = "⏸️ "
+				}
+				if pending := m.pendingDependencies(ticket); len(pending) > 0 {
+					timeInfo = fmt.Sprintf(" (waiting for %s)", joinNumbers(pending))
+				} else if ticket.Attempts > 0 {
+					timeInfo = fmt.Sprintf(" (retrying after: %s)", ticket.FailureReason)
+				}
 			}
 
-			s += fmt.Sprintf("%s Ticket %d: %s%s\n", status, ticket.Number, ticket.Description, timeInfo)
+			lines = append(lines, m.ticketLine(fmt.Sprintf("%s Ticket %d: ", status, ticket.Number), ticket.Description, timeInfo))
 		}
 
+		rest := ""
 		if m.ProcessError != nil {
-			s += "\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.ProcessError)) + "\n"
+			rest += "\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.ProcessError)) + "\n"
+		}
+		rest += m.proposalView()
+		if m.ShowOutput {
+			rest += m.outputView()
 		}
+		rest += m.ticketDetails()
+		pause := "p to pause"
+		if m.Paused {
+			pause = "p to resume"
+		}
+		rest += "\n" + infoStyle.Render("Use ↑/↓ to select a ticket, "+pause+", s to skip, r to retry, x to abort the run, d to toggle details")
+		rest += "\n" + infoStyle.Render("Output: PgUp/PgDn or Ctrl+U/Ctrl+D to scroll, G to jump to the end, F to toggle following, o to hide or show")
+		s += m.ticketListView(lines, m.listHeight(s+rest)) + rest
 
 	case StateCompleted:
-		s += successStyle.Render("All agents completed!") + "\n\n"
+		if m.Aborted {
+			s += errorStyle.Render("Run aborted!") + "\n\n"
+		} else {
+			s += successStyle.Render("All agents completed!") + "\n\n"
+		}
 
-		// Show detailed ticket results with timing
+		// Show ticket results with timing
 		var totalDuration time.Duration
-		successful := 0
-		failed := 0
-
+		lines := []string{}
 		for _, ticket := range m.Tickets {
 			duration := ticket.EndTime.Sub(ticket.StartTime)
 			totalDuration += duration
 
-			status := "✅"
-			if ticket.Failed {
-				status = "❌"
-				failed++
-			} else {
-				successful++
+			reason := ""
+			if ticket.FailureReason != "" {
+				reason = fmt.Sprintf(" (%s)", ticket.FailureReason)
+			} else if ticket.Status == StatusPending {
+				reason = " (not run)"
+			} else if ticket.NoOp {
+				reason = " (no-op)"
 			}
 
-			s += fmt.Sprintf("%s Ticket %d: %s - %s\n",
-				status, ticket.Number, ticket.Description, formatDuration(duration))
+			lines = append(lines, m.ticketLine(fmt.Sprintf("%s Ticket %d: ", ticket.Status.icon(), ticket.Number),
+				ticket.Description, fmt.Sprintf(" - %s%s", formatDuration(duration), reason)))
 		}
 
 		// Show summary
-		s += "\nSummary:\n"
-		s += fmt.Sprintf("✅ Successful: %d\n", successful)
-		s += fmt.Sprintf("❌ Failed: %d\n", failed)
-		s += fmt.Sprintf("📊 Total: %d\n", len(m.Tickets))
-		s += fmt.Sprintf("⏱️  Total time: %s\n", formatDuration(totalDuration))
+		rest := "\nSummary:\n"
+		counts := statusCounts(m.Tickets)
+		for _, status := range summaryStatuses {
+			// Successes and failures are always listed
+			if counts[status] > 0 || status == StatusSucceeded || status == StatusFailed {
+				rest += fmt.Sprintf("%s %s: %d\n", status.icon(), status.label(), counts[status])
+			}
+		}
+		rest += fmt.Sprintf("📊 Total: %d\n", len(m.Tickets))
+		rest += fmt.Sprintf("⏱️  Total time: %s\n", formatDuration(totalDuration))
+
+		rest += m.proposalView()
+		rest += m.ticketDetails()
+		rest += "\n" + infoStyle.Render("Use ↑/↓ to select a ticket, r to retry the selected ticket, d to toggle details, q to quit")
+		s += m.ticketListView(lines, m.listHeight(s+rest)) + rest
+	}
+
+	return s
+}
 
-		s += "\n" + infoStyle.Render("Press q to quit")
+// reportLines renders the agent's completion report below its ticket.
+func reportLines(ticket Ticket) []string {
+	report := ticket.Report
+	if report == nil {
+		return nil
+	}
+	lines := []string{}
+	if report.Summary != "" {
+		lines = append(lines, "📝 "+report.Summary)
+	}
+	if len(report.FilesChanged) > 0 {
+		lines = append(lines, fmt.Sprintf("📁 %d file%s changed: %s", len(report.FilesChanged), plural(len(report.FilesChanged)), strings.Join(report.FilesChanged, ", ")))
+	}
+	for _, followUp := range report.FollowUps {
+		lines = append(lines, "➡️  "+followUp)
+	}
+	return lines
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// joinNumbers formats ticket numbers as "1, 3".
+func joinNumbers(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = fmt.Sprintf("%d", n)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// proposalView asks for approval of the oldest ticket proposed by an agent.
+func (m Model) proposalView() string {
+	if len(m.ProposedTickets) == 0 {
+		return ""
+	}
+	proposal := m.ProposedTickets[0]
+	s := "\n" + selectedStyle.Render(fmt.Sprintf("💡 Ticket %d proposes a new ticket: %s", proposal.ProposedBy, proposal.Description)) + "\n"
+	if proposal.Body != "" {
+		s += proposal.Body + "\n"
+	}
+	hint := "Press y to add it to the run, n to discard it"
+	if m.WriteBackTickets {
+		hint = "Press y to add it to the run and tickets.md, n to discard it"
+	}
+	if more := len(m.ProposedTickets) - 1; more > 0 {
+		hint += fmt.Sprintf(" (%d more waiting)", more)
+	}
+	return s + infoStyle.Render(hint) + "\n"
+}
+
+// outputTailBytes limits how much of a ticket's log the output pane keeps for
+// scrolling back.
+const outputTailBytes = 64 * 1024
+
+// resizeOutput fits the output pane to the terminal: the full width inside
+// its border and a third of the height. Until the terminal size is known it
+// assumes 80x24.
+func (m Model) resizeOutput() Model {
+	width, height := m.Width, m.Height
+	if width == 0 || height == 0 {
+		width, height = 80, 24
+	}
+	m.Output.Width = max(width-2, 10)
+	m.Output.Height = max(height/3-2, 3)
+	if m.FollowOutput {
+		m.Output.GotoBottom()
+	}
+	return m
+}
+
+// refreshOutput loads the latest output of the ticket under the cursor into
+// the output pane.
+func (m Model) refreshOutput() Model {
+	content := ""
+	if m.Cursor >= 0 && m.Cursor < len(m.Tickets) {
+		ticket := m.Tickets[m.Cursor]
+		content = cleanOutput(logTail(ticket.LogPath, outputTailBytes))
+		if ticket.LogPath == "" {
+			content = infoStyle.Render("(not started)")
+		} else if content == "" {
+			content = infoStyle.Render("(no output yet)")
+		}
+	}
+	m.Output.SetContent(content)
+	if m.FollowOutput {
+		m.Output.GotoBottom()
+	}
+	return m
+}
+
+// cleanOutput keeps what a terminal would show of lines that were redrawn
+// with carriage returns, such as progress bars.
+func cleanOutput(output string) string {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = strings.ReplaceAll(line, "\t", "    ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// outputView renders the output pane for the ticket under the cursor.
+func (m Model) outputView() string {
+	if m.Cursor < 0 || m.Cursor >= len(m.Tickets) {
+		return ""
+	}
+	title := fmt.Sprintf("Ticket %d output", m.Tickets[m.Cursor].Number)
+	if m.FollowOutput {
+		title += " (following)"
+	} else {
+		title += fmt.Sprintf(" (%d%%, G to follow)", int(m.Output.ScrollPercent()*100))
+	}
+	return "\n" + selectedStyle.Render(title) + "\n" + outputStyle.Render(m.Output.View()) + "\n"
+}
+
+// ticketListView renders one line per ticket, truncated to the terminal
+// width. A list longer than height is scrolled so the ticket under the cursor
+// stays visible. A height of zero shows every ticket.
+func (m Model) ticketListView(lines []string, height int) string {
+	start, end := 0, len(lines)
+	if height > 0 && len(lines) > height {
+		// Leave room for the lines saying how many tickets are hidden
+		rows := max(height-2, 1)
+		start = min(max(m.Cursor-rows/2, 0), len(lines)-rows)
+		end = start + rows
 	}
 
+	s := ""
+	if start > 0 {
+		s += infoStyle.Render(fmt.Sprintf("  ↑ %d more", start)) + "\n"
+	}
+	for i := start; i < end; i++ {
+		line := truncate(lines[i], m.Width-2)
+		if i == m.Cursor {
+			s += selectedStyle.Render("→ "+line) + "\n"
+		} else {
+			s += "  " + line + "\n"
+		}
+	}
+	if end < len(lines) {
+		s += infoStyle.Render(fmt.Sprintf("  ↓ %d more", len(lines)-end)) + "\n"
+	}
 	return s
 }
 
+// ticketLine joins a ticket's status, description and timing into one list
+// line. When it is too wide for the terminal, the description is shortened
+// first so the status and timing stay readable.
+func (m Model) ticketLine(prefix, description, suffix string) string {
+	line := prefix + description + suffix
+	width := m.Width - 2 // Room for the cursor
+	if m.Width == 0 || lipgloss.Width(line) <= width {
+		return line
+	}
+	room := width - lipgloss.Width(prefix) - lipgloss.Width(suffix)
+	if room < 10 {
+		return line
+	}
+	return prefix + truncate(description, room) + suffix
+}
+
+// listHeight is the number of lines left for the ticket list once the rest
+// of the view is drawn, counting lines the terminal wraps. It is zero until
+// the terminal size is known.
+func (m Model) listHeight(rest string) int {
+	if m.Height == 0 {
+		return 0
+	}
+	used := 0
+	for _, line := range strings.Split(rest, "\n") {
+		used += max((lipgloss.Width(line)+m.Width-1)/max(m.Width, 1), 1)
+	}
+	return max(m.Height-used, 3)
+}
+
+// truncate shortens a line to the given terminal width, marking the cut with
+// an ellipsis. A width of zero or less leaves the line alone.
+func truncate(line string, width int) string {
+	if width <= 0 || lipgloss.Width(line) <= width {
+		return line
+	}
+	kept := []rune{}
+	used := 0
+	for _, r := range line {
+		w := lipgloss.Width(string(r))
+		if used+w > width-1 {
+			break
+		}
+		kept = append(kept, r)
+		used += w
+	}
+	return string(kept) + "…"
+}
+
+// ticketDetails renders the detail pane of the highlighted ticket: how its
+// last attempt ended, where its work went and its body. Once the terminal
+// size is known the pane takes at most a quarter of the height.
+func (m Model) ticketDetails() string {
+	if !m.ShowDetails || m.Cursor < 0 || m.Cursor >= len(m.Tickets) {
+		return ""
+	}
+	ticket := m.Tickets[m.Cursor]
+	info := []string{}
+	if ticket.finished() || ticket.FailureReason != "" {
+		result := ticket.Status.icon() + " " + ticket.Status.label()
+		if ticket.FailureReason != "" {
+			result += ": " + ticket.FailureReason
+		}
+		if ticket.ExitCode != 0 {
+			result += fmt.Sprintf(" (exit code %d)", ticket.ExitCode)
+		}
+		info = append(info, result)
+	}
+
+	// Metadata that changes how the ticket runs
+	metadata := []string{}
+	if ticket.Agent != "" {
+		metadata = append(metadata, "agent: "+ticket.Agent)
+	}
+	if ticket.Dir != "" {
+		metadata = append(metadata, "dir: "+ticket.Dir)
+	}
+	if ticket.Timeout > 0 {
+		metadata = append(metadata, "timeout: "+ticket.Timeout.String())
+	}
+	if ticket.Retries != nil {
+		metadata = append(metadata, fmt.Sprintf("retries: %d", *ticket.Retries))
+	}
+	if len(ticket.Dependencies) > 0 {
+		metadata = append(metadata, "depends on: "+joinNumbers(ticket.Dependencies))
+	}
+	if len(ticket.Labels) > 0 {
+		metadata = append(metadata, "labels: "+strings.Join(ticket.Labels, ", "))
+	}
+	if len(metadata) > 0 {
+		info = append(info, strings.Join(metadata, " · "))
+	}
+
+	if len(ticket.History) > 1 {
+		for _, attempt := range ticket.History {
+			info = append(info, "↻ "+attempt.String())
+		}
+	}
+	info = append(info, reportLines(ticket)...)
+	if ticket.LogPath != "" {
+		info = append(info, "📄 "+ticket.LogPath)
+	}
+	if ticket.VerifyLogPath != "" {
+		info = append(info, "🔍 "+ticket.VerifyLogPath)
+	}
+	if ticket.Branch != "" {
+		info = append(info, fmt.Sprintf("🌿 %s (%s)", ticket.Branch, ticket.WorktreePath))
+	}
+	if ticket.CommitSHA != "" {
+		info = append(info, fmt.Sprintf("📦 %s %s", shortSHA(ticket.CommitSHA), diffStatSummary(ticket.DiffStat)))
+	}
+
+	lines := []string{}
+	for _, line := range info {
+		lines = append(lines, infoStyle.Render(truncate(line, m.Width)))
+	}
+	if ticket.Body == "" {
+		lines = append(lines, infoStyle.Render("(no details)"))
+	} else {
+		for _, line := range strings.Split(ticket.Body, "\n") {
+			lines = append(lines, truncate(line, m.Width))
+		}
+	}
+	if limit := max(m.Height/4, 4); m.Height > 0 && len(lines) > limit {
+		hidden := len(lines) - limit + 1
+		lines = append(lines[:limit-1], infoStyle.Render(fmt.Sprintf("… %d more lines", hidden)))
+	}
+
+	return "\n" + selectedStyle.Render(fmt.Sprintf("Ticket %d details:", ticket.Number)) + "\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// headlessModel drives the regular Model without rendering. It is used by the
+// `run` subcommand and prints one line per ticket transition instead.
+type headlessModel struct {
+	Model
+	out          io.Writer
+	initCmd      tea.Cmd
+	started      map[int]bool
+	finished     map[int]bool
+	attempts     map[int]int // Last attempt reported per ticket
+	tickets      int         // Number of tickets reported so far
+	proposed     int         // Number of unapproved proposals reported so far
+	waitingUntil time.Time   // End of the last wait reported
+}
+
+func (h headlessModel) Init() tea.Cmd {
+	return h.initCmd
+}
+
+func (h headlessModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// There is no keyboard in headless mode, so never let a stray key reach
+	// the TUI handlers (q would kill the agent).
+	if _, ok := msg.(tea.KeyMsg); ok {
+		return h, nil
+	}
+
+	next, cmd := h.Model.Update(msg)
+	h.Model = next.(Model)
+	h.report()
+
+	if h.State == StateCompleted {
+		return h, tea.Quit
+	}
+	return h, cmd
+}
+
+func (h headlessModel) View() string {
+	return ""
+}
+
+// report prints every ticket transition that happened since the last call.
+func (h *headlessModel) report() {
+	for _, ticket := range h.Tickets[h.tickets:] {
+		h.logf("➕ Added ticket %d proposed by ticket %d: %s", ticket.Number, ticket.ProposedBy, ticket.Description)
+	}
+	h.tickets = len(h.Tickets)
+	for _, proposal := range h.ProposedTickets[h.proposed:] {
+		h.logf("💡 Ticket %d proposed a new ticket, not added without -approve-follow-ups: %s", proposal.ProposedBy, proposal.Description)
+	}
+	h.proposed = len(h.ProposedTickets)
+
+	for i, ticket := range h.Tickets {
+		if ticket.Attempts > 1 && ticket.Attempts > h.attempts[i] {
+			if n := len(ticket.History); n > 0 && ticket.History[n-1].Status == StatusRateLimited {
+				h.logf("🔁 Retrying ticket %d after: %s", ticket.Number, ticket.FailureReason)
+			} else {
+				h.logf("🔁 Retrying ticket %d (attempt %d of %d) after: %s", ticket.Number, ticket.countedAttempts(), h.ticketRetries(ticket)+1, ticket.FailureReason)
+			}
+		}
+		h.attempts[i] = max(h.attempts[i], ticket.Attempts)
+
+		if !ticket.StartTime.IsZero() && !h.started[i] {
+			h.started[i] = true
+			h.logf("▶ Ticket %d: %s", ticket.Number, ticket.Description)
+		}
+
+		if ticket.finished() && !h.finished[i] {
+			h.finished[i] = true
+			duration := formatDuration(ticket.EndTime.Sub(ticket.StartTime))
+			switch ticket.Status {
+			case StatusVerifyFailed:
+				h.logf("🧪 Ticket %d failed verification after %s: %s", ticket.Number, duration, ticket.FailureReason)
+			case StatusTimedOut:
+				h.logf("⌛ Ticket %d %s", ticket.Number, ticket.FailureReason)
+			case StatusCrashed:
+				h.logf("💥 Ticket %d crashed after %s: %s", ticket.Number, duration, ticket.FailureReason)
+			case StatusBlocked:
+				h.logf("🚫 Ticket %d not started: %s", ticket.Number, ticket.FailureReason)
+			case StatusSkipped:
+				h.logf("⏭️  Ticket %d skipped", ticket.Number)
+			case StatusFailed:
+				if ticket.FailureReason != "" {
+					h.logf("❌ Ticket %d failed after %s: %s", ticket.Number, duration, ticket.FailureReason)
+				} else {
+					h.logf("❌ Ticket %d failed after %s", ticket.Number, duration)
+				}
+			case StatusUnknown:
+				h.logf("❔ Ticket %d exited after %s without reporting a result", ticket.Number, duration)
+			default:
+				if ticket.NoOp {
+					h.logf("✅ Ticket %d completed in %s without changes (no-op)", ticket.Number, duration)
+				} else {
+					h.logf("✅ Ticket %d completed in %s", ticket.Number, duration)
+				}
+			}
+			if report := ticket.Report; report != nil {
+				if report.Summary != "" {
+					h.logf("   summary: %s", report.Summary)
+				}
+				if len(report.FilesChanged) > 0 {
+					h.logf("   files changed: %s", strings.Join(report.FilesChanged, ", "))
+				}
+				for _, followUp := range report.FollowUps {
+					h.logf("   follow-up: %s", followUp)
+				}
+			}
+			if ticket.LogPath != "" {
+				h.logf("   log: %s", ticket.LogPath)
+			}
+			if ticket.VerifyLogPath != "" {
+				h.logf("   verify log: %s", ticket.VerifyLogPath)
+			}
+			if ticket.Branch != "" {
+				h.logf("   branch: %s (worktree: %s)", ticket.Branch, ticket.WorktreePath)
+			}
+			if ticket.CommitSHA != "" {
+				h.logf("   commit: %s %s", shortSHA(ticket.CommitSHA), diffStatSummary(ticket.DiffStat))
+			}
+		}
+	}
+
+	if h.IsWaiting && !h.WaitingUntil.Equal(h.waitingUntil) {
+		if h.WaitReason != "" {
+			h.logf("⏳ Waiting %s before next agent: %s", formatDuration(time.Until(h.WaitingUntil).Round(time.Second)), h.WaitReason)
+		} else {
+			h.logf("⏳ Waiting %d seconds before next agent", h.DelaySeconds)
+		}
+		h.waitingUntil = h.WaitingUntil
+	}
+}
+
+func (h *headlessModel) logf(format string, args ...interface{}) {
+	fmt.Fprintf(h.out, "[%s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+}
+
+// runHeadless implements the `run` subcommand. It returns the process exit
+// code: 0 when every ticket succeeded, 1 when any ticket failed and 2 for
+// usage or setup errors.
+func runHeadless(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	project := fs.String("project", "", "project folder under input/ to run (required)")
+	agent := fs.String("agent", "", "agent command, the prompt is appended as the last argument (default: "+defaultAgent.String()+")")
+	profileName := fs.String("profile", "", "name of an agent profile from "+agentProfilesFile)
+	promptMode := fs.String("prompt", "", "how the prompt is delivered: argv, stdin or file (default: the profile's mode)")
+	delay := fs.Int("delay", 2, "seconds to wait between agents")
+	timeout := fs.Duration("timeout", 0, "per-ticket timeout, e.g. 30m (0 disables it)")
+	grace := fs.Duration("grace", 10*time.Second, "time between SIGTERM and SIGKILL when a ticket times out")
+	concurrency := fs.Int("concurrency", 1, "maximum number of agents running at once")
+	retries := fs.Int("retries", 0, "how often a failed ticket is started again, unless its metadata says otherwise")
+	worktrees := fs.Bool("worktrees", false, "run each ticket in its own git worktree and branch")
+	commit := fs.Bool("commit", false, "commit each successful ticket's changes")
+	approveFollowUps := fs.Bool("approve-follow-ups", false, "add tickets proposed by agents to the run")
+	writeFollowUps := fs.Bool("write-follow-ups", false, "append approved follow-up tickets to tickets.md")
+	resume := fs.Bool("resume", false, "continue the project's unfinished run from its first unfinished ticket")
+	ticketSpec := fs.String("tickets", "", "tickets to run in this order, e.g. 3,1-2 (default: all)")
+	onlyFailed := fs.Bool("only-failed", false, "run only the tickets that failed in the project's previous run")
+	onCleanExit := fs.String("on-clean-exit", string(ExitPolicyUnknown), "outcome when an agent exits 0 without writing its kill file: success, failure or unknown")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *project == "" {
+		fmt.Fprintln(os.Stderr, "run: -project is required")
+		fs.Usage()
+		return 2
+	}
+	if *delay < 0 {
+		fmt.Fprintln(os.Stderr, "run: -delay must not be negative")
+		return 2
+	}
+	if *concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "run: -concurrency must be at least 1")
+		return 2
+	}
+	if *commit && *concurrency > 1 && !*worktrees {
+		fmt.Fprintln(os.Stderr, "run: -commit with -concurrency above 1 needs -worktrees")
+		return 2
+	}
+	if *resume && (*ticketSpec != "" || *onlyFailed) {
+		fmt.Fprintln(os.Stderr, "run: -tickets and -only-failed cannot be combined with -resume")
+		return 2
+	}
+	if *retries < 0 {
+		fmt.Fprintln(os.Stderr, "run: -retries must not be negative")
+		return 2
+	}
+	if *timeout < 0 || *grace < 0 {
+		fmt.Fprintln(os.Stderr, "run: -timeout and -grace must not be negative")
+		return 2
+	}
+	exitPolicy, err := parseExitPolicy(*onCleanExit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return 2
+	}
+	// Tickets may name a profile even when the run uses -agent
+	profiles, err := loadAgentProfiles(agentProfilesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return 2
+	}
+	agentProfile := profiles[0]
+	switch {
+	case *agent != "" && *profileName != "":
+		fmt.Fprintln(os.Stderr, "run: -agent and -profile cannot be combined")
+		return 2
+	case *agent != "":
+		agentProfile, err = customProfile(*agent)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "run: -agent: %v\n", err)
+			return 2
+		}
+	case *profileName != "":
+		var ok bool
+		if agentProfile, ok = findProfile(profiles, *profileName); !ok {
+			fmt.Fprintf(os.Stderr, "run: no agent profile named %q in %s\n", *profileName, agentProfilesFile)
+			return 2
+		}
+	}
+
+	result := checkFiles(*project)
+	if len(result.MissingFiles) > 0 {
+		fmt.Fprintf(out, "Missing files in input/%s: %s\n", *project, strings.Join(result.MissingFiles, ", "))
+		return 2
+	}
+	if len(result.ParsedTickets) == 0 {
+		fmt.Fprintf(out, "No tickets found in input/%s/tickets.md\n", *project)
+		return 2
+	}
+	if result.PromptError != nil {
+		fmt.Fprintf(out, "Invalid template in input/%s/standard-prompt.md: %v\n", *project, result.PromptError)
+		return 2
+	}
+	if result.VerifyError != nil {
+		fmt.Fprintf(out, "Cannot read input/%s/verify.txt: %v\n", *project, result.VerifyError)
+		return 2
+	}
+
+	m := initialModel().selectProject(*project)
+	if *promptMode != "" {
+		agentProfile.Prompt, err = parsePromptMode(*promptMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "run: %v\n", err)
+			return 2
+		}
+	}
+	m.Profiles = profiles
+	m.Agent = agentProfile
+	m.DelaySeconds = *delay
+	m.ExitPolicy = exitPolicy
+	m.Timeout = *timeout
+	m.GracePeriod = *grace
+	m.Concurrency = *concurrency
+	m.Retries = *retries
+	m.UseWorktrees = *worktrees
+	m.AutoCommit = *commit
+	m.AutoApprove = *approveFollowUps
+	m.WriteBackTickets = *writeFollowUps
+	m.Tickets = result.ParsedTickets
+	m.VerifyCommands = result.VerifyCommands
+	if *resume {
+		state, err := loadRunState(*project)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			return 2
+		}
+		if state != nil {
+			m.ResumeState = state
+			m.Tickets = mergeRunState(m.Tickets, state)
+			m.RunDir = state.RunDir
+		}
+	}
+	if *ticketSpec != "" || *onlyFailed {
+		numbers := []int{}
+		for _, ticket := range m.Tickets {
+			numbers = append(numbers, ticket.Number)
+		}
+		if *ticketSpec != "" {
+			numbers, err = parseTicketSelection(*ticketSpec, m.Tickets)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "run: -tickets: %v\n", err)
+				return 2
+			}
+		}
+		if *onlyFailed {
+			previous, err := loadRunState(*project)
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(out, "Error: %v\n", err)
+				return 2
+			}
+			failed := previous.failedTickets()
+			onlyFailedNumbers := []int{}
+			for _, number := range numbers {
+				if failed[number] {
+					onlyFailedNumbers = append(onlyFailedNumbers, number)
+				}
+			}
+			numbers = onlyFailedNumbers
+		}
+		if len(numbers) == 0 {
+			fmt.Fprintln(os.Stderr, "run: no tickets selected")
+			return 2
+		}
+		m.Tickets = selectTickets(m.Tickets, numbers)
+	}
+
+	h := headlessModel{
+		out:      out,
+		started:  map[int]bool{},
+		finished: map[int]bool{},
+		attempts: map[int]int{},
+		tickets:  len(m.Tickets),
+	}
+	// Tickets finished by a resumed run are not reported again, and starting
+	// an interrupted ticket again is no retry
+	for i, ticket := range m.Tickets {
+		if ticket.finished() {
+			h.started[i] = true
+			h.finished[i] = true
+		} else if ticket.Attempts > 0 {
+			h.attempts[i] = ticket.Attempts + 1
+		}
+	}
+	for _, path := range findStaleKillFiles(m.RunDir) {
+		h.logf("⚠️  Removing stale kill file %s", path)
+	}
+	for _, warning := range []string{m.worktreeWarning(), m.resumeAgentWarning()} {
+		if warning != "" {
+			h.logf("⚠️  %s", warning)
+		}
+	}
+	h.Model, h.initCmd = m.startRun()
+	h.logf("Running %d tickets for project %s with agent %q", len(h.Tickets), *project, m.Agent.String())
+	if m.ResumeState != nil && h.State == StateRunning {
+		h.logf("Resuming from ticket %d", h.Tickets[nextUnfinishedTicket(h.Tickets, 0)].Number)
+	}
+
+	// A resumed run may have nothing left to do
+	if h.State == StateRunning {
+		p := tea.NewProgram(h, tea.WithInput(nil), tea.WithoutRenderer())
+		final, err := p.Run()
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			return 2
+		}
+		h = final.(headlessModel)
+	}
+
+	if h.State != StateCompleted {
+		// Interrupted by a signal before the queue finished
+		for _, agent := range h.Running {
+			agent.stop()
+		}
+		h.logf("Interrupted")
+		return 1
+	}
+
+	counts := statusCounts(h.Tickets)
+	summary := []string{}
+	failed := false
+	for _, status := range summaryStatuses {
+		if counts[status] > 0 || status == StatusSucceeded || status == StatusFailed || status == StatusUnknown {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[status], strings.ToLower(status.label())))
+		}
+		failed = failed || (status.failed() && counts[status] > 0)
+	}
+	h.logf("Summary: %s, %d total", strings.Join(summary, ", "), len(h.Tickets))
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runHeadless(os.Args[2:], os.Stdout))
+	}
+
 	p := tea.NewProgram(initialModel())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 func TestParseTickets(t *testing.T) {
@@ -122,7 +132,7 @@ Some description
 			expectedDescs:   []string{"Extra spaces", "More spaces", ""},
 		},
 		{
-			name: "Empty file",
+			name:            "Empty file",
 			content:         ``,
 			expectedCount:   0,
 			expectedNumbers: []int{},
@@ -188,6 +198,115 @@ Some description
 	}
 }
 
+func TestParseTicketsTimeout(t *testing.T) {
+	tmpfile := t.TempDir() + "/tickets.md"
+	content := `## Ticket 1: Quick
+Timeout: 90s
+- some detail
+## Ticket 2: Default
+## Ticket 3: Invalid override
+timeout: soon
+## Ticket 4: Long
+TIMEOUT: 2h`
+	if err := os.WriteFile(tmpfile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tickets, err := parseTickets(tmpfile)
+	if err != nil {
+		t.Fatalf("parseTickets() error = %v", err)
+	}
+
+	expected := []time.Duration{90 * time.Second, 0, 0, 2 * time.Hour}
+	for i, want := range expected {
+		if tickets[i].Timeout != want {
+			t.Errorf("ticket[%d].Timeout = %v, want %v", i, tickets[i].Timeout, want)
+		}
+	}
+}
+
+func TestParseTicketsBody(t *testing.T) {
+	tmpfile := t.TempDir() + "/tickets.md"
+	content := `# Project Tickets
+Intro text that belongs to no ticket
+
+## Ticket 2: Second
+- Step one
+  - Nested detail
+
+## Ticket 1: First
+Do the first thing.
+### Notes
+Keep it short.
+## Ticket 3: Empty
+`
+	if err := os.WriteFile(tmpfile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tickets, err := parseTickets(tmpfile)
+	if err != nil {
+		t.Fatalf("parseTickets() error = %v", err)
+	}
+
+	expected := []string{
+		"Do the first thing.\n### Notes\nKeep it short.",
+		"- Step one\n  - Nested detail",
+		"",
+	}
+	for i, want := range expected {
+		if tickets[i].Body != want {
+			t.Errorf("ticket[%d].Body = %q, want %q", i, tickets[i].Body, want)
+		}
+	}
+}
+
+func TestParseTicketsDependencies(t *testing.T) {
+	tmpfile := t.TempDir() + "/tickets.md"
+	content := `## Ticket 1: Base
+## Ticket 2: Needs one and three
+Depends: 1, 3
+## Ticket 3: Hash style
+Depends on: #1 #3
+## Ticket 4: None
+## Ticket 5: Below the description
+Build on the first ticket.
+
+Depends: 1
+Timeout: 5m
+## Ticket 6: Samples and prose
+Call the API from ticket 3.
+Depends on: the API from ticket 3
+` + "```yaml\nserver:\n  timeout: 5s\nDepends: 1\n```" + `
+~~~
+Timeout: 1s
+~~~`
+	if err := os.WriteFile(tmpfile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tickets, err := parseTickets(tmpfile)
+	if err != nil {
+		t.Fatalf("parseTickets() error = %v", err)
+	}
+
+	// A ticket never depends on itself
+	expected := [][]int{nil, {1, 3}, {1}, nil, {1}, nil}
+	for i, want := range expected {
+		if fmt.Sprint(tickets[i].Dependencies) != fmt.Sprint(want) {
+			t.Errorf("ticket[%d].Dependencies = %v, want %v", i, tickets[i].Dependencies, want)
+		}
+	}
+	// Lines below the description are kept in the body
+	if tickets[4].Timeout != 5*time.Minute || !strings.Contains(tickets[4].Body, "Depends: 1") {
+		t.Errorf("ticket[4] = %+v, want a 5m timeout and the Depends line in its body", tickets[4])
+	}
+	// Prose and code blocks set no metadata
+	if tickets[5].Timeout != 0 || !strings.Contains(tickets[5].Body, "  timeout: 5s") {
+		t.Errorf("ticket[5] = %+v, want no timeout and the code blocks in its body", tickets[5])
+	}
+}
+
 func TestParseTicketsFileError(t *testing.T) {
 	// Test with non-existent file
 	_, err := parseTickets("/non/existent/file.md")
@@ -337,4 +456,1941 @@ func BenchmarkParseTickets(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		parseTickets(tmpfile.Name())
 	}
-}
\ No newline at end of file
+}
+
+// writeTestProject creates input/<project> with the three required files in
+// dir and returns the project directory.
+func writeTestProject(t *testing.T, dir, project, tickets string) string {
+	t.Helper()
+	projectDir := dir + "/input/" + project
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"specification.md":   "# Spec",
+		"tickets.md":         tickets,
+		"standard-prompt.md": "Standard prompt",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(projectDir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return projectDir
+}
+
+// chdir switches into dir for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(oldWd) })
+}
+
+func TestRunHeadless(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n## Ticket 2: Second\n")
+	chdir(t, tmpDir)
+
+	// The agent fails the second ticket by writing "failure" to the kill file
+	agent := `echo "working on it"; if [ -f first-done ]; then echo failure > "$KILL_FILE"; else touch first-done; echo success > "$KILL_FILE"; fi; sleep 5`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out)
+
+	if code != 1 {
+		t.Errorf("runHeadless() = %d, want 1\n%s", code, out.String())
+	}
+	for _, want := range []string{"✅ Ticket 1 completed", "❌ Ticket 2 failed", "1 successful, 1 failed, 0 unknown, 2 total"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// Each ticket's output ends up in its own log file under runs/
+	logs, err := filepath.Glob("runs/demo/*/ticket-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("found %d log files, want 2: %v", len(logs), logs)
+	}
+	for _, logPath := range logs {
+		content, err := os.ReadFile(logPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "working on it") {
+			t.Errorf("%s = %q, want agent output", logPath, content)
+		}
+	}
+}
+
+func TestRunHeadlessAgentExit(t *testing.T) {
+	tests := []struct {
+		name         string
+		agent        string
+		policy       string
+		expectedCode int
+		expectedLine string
+	}{
+		{
+			name:         "Non-zero exit fails the ticket",
+			agent:        "exit 3",
+			policy:       "success",
+			expectedCode: 1,
+			expectedLine: "agent exited with code 3",
+		},
+		{
+			name:         "Clean exit with success policy",
+			agent:        "exit 0",
+			policy:       "success",
+			expectedCode: 0,
+			expectedLine: "✅ Ticket 1 completed",
+		},
+		{
+			name:         "Clean exit with failure policy",
+			agent:        "exit 0",
+			policy:       "failure",
+			expectedCode: 1,
+			expectedLine: "❌ Ticket 1 failed",
+		},
+		{
+			name:         "Clean exit with unknown policy",
+			agent:        "exit 0",
+			policy:       "unknown",
+			expectedCode: 0,
+			expectedLine: "❔ Ticket 1 exited",
+		},
+		{
+			name:         "Kill file written right before exit",
+			agent:        `echo success > "$KILL_FILE"; exit 0`,
+			policy:       "failure",
+			expectedCode: 0,
+			expectedLine: "✅ Ticket 1 completed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeTestProject(t, tmpDir, "demo", "## Ticket 1: Only\n")
+			chdir(t, tmpDir)
+			if err := os.WriteFile("agent.sh", []byte(tt.agent), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			var out strings.Builder
+			code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-on-clean-exit", tt.policy}, &out)
+
+			if code != tt.expectedCode {
+				t.Errorf("runHeadless() = %d, want %d\n%s", code, tt.expectedCode, out.String())
+			}
+			if !strings.Contains(out.String(), tt.expectedLine) {
+				t.Errorf("output missing %q:\n%s", tt.expectedLine, out.String())
+			}
+		})
+	}
+}
+
+func TestRunHeadlessTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent string
+	}{
+		{name: "Agent stops on SIGTERM", agent: "sleep 30"},
+		{name: "Agent ignores SIGTERM", agent: "trap '' TERM; sleep 30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeTestProject(t, tmpDir, "demo", "## Ticket 1: Hangs\n")
+			chdir(t, tmpDir)
+			if err := os.WriteFile("agent.sh", []byte(tt.agent), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			var out strings.Builder
+			code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-timeout", "300ms", "-grace", "300ms"}, &out)
+
+			if code != 1 {
+				t.Errorf("runHeadless() = %d, want 1\n%s", code, out.String())
+			}
+			if !strings.Contains(out.String(), "timed out after 300ms") {
+				t.Errorf("output missing timeout reason:\n%s", out.String())
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("run took %s, the agent was not killed", elapsed)
+			}
+		})
+	}
+}
+
+func TestPromptUsesTicketNumber(t *testing.T) {
+	tmpDir := t.TempDir()
+	tickets := `## Ticket 30: Thirty
+Body of thirty
+## Ticket 10: Ten
+Body of ten
+## Ticket 20: Twenty
+`
+	writeTestProject(t, tmpDir, "demo", tickets)
+	chdir(t, tmpDir)
+
+	// The agent records each prompt it receives, separated by a marker line
+	agent := `printf '%s\n=====\n' "$1" >> prompts.txt; echo success > "$KILL_FILE"`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+
+	content, err := os.ReadFile("prompts.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts := strings.Split(strings.TrimSuffix(string(content), "=====\n"), "=====\n")
+	if len(prompts) != 3 {
+		t.Fatalf("agent received %d prompts, want 3:\n%s", len(prompts), content)
+	}
+
+	expected := []struct {
+		workOn string
+		body   string
+	}{
+		{"Please work on ticket 10: Ten.", "Body of ten"},
+		{"Please work on ticket 20: Twenty.", ""},
+		{"Please work on ticket 30: Thirty.", "Body of thirty"},
+	}
+	for i, want := range expected {
+		if !strings.Contains(prompts[i], want.workOn) {
+			t.Errorf("prompt %d does not contain %q:\n%s", i, want.workOn, prompts[i])
+		}
+		if want.body != "" && !strings.Contains(prompts[i], want.body) {
+			t.Errorf("prompt %d does not contain body %q:\n%s", i, want.body, prompts[i])
+		}
+		if strings.Contains(prompts[i], fmt.Sprintf("work on ticket %d:", i+1)) {
+			t.Errorf("prompt %d refers to the queue position instead of the ticket number:\n%s", i, prompts[i])
+		}
+	}
+}
+
+func TestTicketStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to TicketStatus
+		want     bool
+	}{
+		{StatusPending, StatusRunning, true},
+		{StatusPending, StatusSucceeded, false},
+		{StatusRunning, StatusTimedOut, true},
+		{StatusRunning, StatusPending, true},
+		{StatusRateLimited, StatusRunning, true},
+		{StatusSkipped, StatusSucceeded, false},
+		{StatusSucceeded, StatusFailed, false},
+		{StatusCrashed, StatusPending, true},
+		{StatusBlocked, StatusRunning, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.canBecome(tt.to); got != tt.want {
+			t.Errorf("%q.canBecome(%q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	// A late result for a skipped ticket is ignored
+	m := initialModel()
+	m.Tickets = []Ticket{{Number: 1, Status: StatusSkipped}}
+	if m.setStatus(0, StatusSucceeded) || m.Tickets[0].Status != StatusSkipped {
+		t.Errorf("setStatus() changed a skipped ticket to %q", m.Tickets[0].Status)
+	}
+}
+
+func TestRunHeadlessResume(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n## Ticket 2: Second\n## Ticket 3: Third\n")
+	chdir(t, tmpDir)
+
+	// Simulate a run that was interrupted after the first ticket
+	m := initialModel().selectProject("demo")
+	m.RunDir = "runs/demo/earlier"
+	m.Agent = defaultAgent
+	m.Tickets = []Ticket{
+		{Number: 1, Description: "First", Status: StatusSucceeded, StartTime: time.Now(), EndTime: time.Now()},
+		{Number: 2, Description: "Second", StartTime: time.Now()},
+		{Number: 3, Description: "Third", Status: StatusCancelled, Attempts: 1, Uncounted: 1, LogPath: "runs/demo/earlier/ticket-3.log",
+			History: []Attempt{{Number: 1, Status: StatusCancelled, FailureReason: "run aborted"}}},
+	}
+	if err := m.saveRunState(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The saved run is offered on the project selection screen
+	scanned := scanProjects().(projectsScannedMsg)
+	if state, ok := scanned.savedRuns["demo"]; !ok || state.finishedCount() != 1 {
+		t.Fatalf("scanProjects() savedRuns = %v, want demo with 1 finished ticket", scanned.savedRuns)
+	}
+
+	agent := `printf '%s\n' "$1" | grep -o 'work on ticket [0-9]*' >> prompts.txt; echo success > "$KILL_FILE"`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-resume"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+
+	content, err := os.ReadFile("prompts.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "work on ticket 2\nwork on ticket 3\n"; got != want {
+		t.Errorf("agent ran %q, want %q", got, want)
+	}
+	if strings.Contains(out.String(), "Retrying") {
+		t.Errorf("output reports the interrupted ticket as retried:\n%s", out.String())
+	}
+	if want := fmt.Sprintf("The saved run used the agent %q, resuming with %q", defaultAgent.String(), "sh agent.sh"); !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+
+	// The resumed run keeps its log directory and records every ticket
+	state, err := loadRunState("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.finishedCount() != 3 || state.resumable() {
+		t.Errorf("saved state has %d finished tickets, want all 3", state.finishedCount())
+	}
+	if state.Tickets[1].LogPath != filepath.Join("runs/demo/earlier", "ticket-2.log") {
+		t.Errorf("ticket 2 log = %q, want it in the resumed run directory", state.Tickets[1].LogPath)
+	}
+	// The interrupted ticket keeps its earlier attempt
+	if ticket := state.Tickets[2]; ticket.Attempts != 2 || len(ticket.History) != 2 || filepath.Base(ticket.LogPath) != "ticket-3-attempt-2.log" {
+		t.Errorf("ticket 3 = %+v, want its second attempt recorded after the first", ticket)
+	}
+}
+
+func TestRunHeadlessResumeSelection(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n## Ticket 2: Second\n## Ticket 3: Third\n## Ticket 4: Fourth\n")
+	chdir(t, tmpDir)
+
+	// The interrupted run was chosen as 3, 1, 4 and left out ticket 2
+	m := initialModel().selectProject("demo")
+	m.RunDir = "runs/demo/earlier"
+	m.Tickets = []Ticket{
+		{Number: 3, Description: "Third", Status: StatusSucceeded, StartTime: time.Now(), EndTime: time.Now()},
+		{Number: 1, Description: "First"},
+		{Number: 4, Description: "Fourth"},
+	}
+	if err := m.saveRunState(); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := `printf '%s\n' "$1" | grep -o 'work on ticket [0-9]*' >> prompts.txt; echo success > "$KILL_FILE"`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-resume"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	content, err := os.ReadFile("prompts.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "work on ticket 1\nwork on ticket 4\n"; got != want {
+		t.Errorf("agent ran %q, want %q", got, want)
+	}
+
+	state, err := loadRunState("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.Selection, []int{3, 1, 4}) {
+		t.Errorf("saved Selection = %v, want [3 1 4]", state.Selection)
+	}
+}
+
+// parallelAgent records when it starts and ends work on a ticket and reports
+// the result through the ticket's own kill file. Ticket 1 fails when the
+// FAIL_FIRST file exists.
+const parallelAgent = `n=$(printf '%s' "$1" | grep -o 'work on ticket [0-9]*' | grep -o '[0-9]*$')
+echo "start $n" >> events.txt
+sleep 1
+echo "end $n" >> events.txt
+if [ "$n" = 1 ] && [ -f FAIL_FIRST ]; then echo failure > "$KILL_FILE"; else echo success > "$KILL_FILE"; fi
+`
+
+func TestRunHeadlessParallelDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	tickets := `## Ticket 1: Base
+## Ticket 2: After base
+Depends: 1
+## Ticket 3: Independent
+`
+	writeTestProject(t, tmpDir, "demo", tickets)
+	chdir(t, tmpDir)
+	if err := os.WriteFile("agent.sh", []byte(parallelAgent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-concurrency", "2"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+
+	content, err := os.ReadFile("events.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := strings.Split(strings.TrimSpace(string(content)), "\n")
+	position := map[string]int{}
+	for i, event := range events {
+		position[event] = i
+	}
+
+	// Tickets 1 and 3 run side by side, ticket 2 waits for ticket 1
+	if position["start 3"] > position["end 1"] {
+		t.Errorf("ticket 3 did not run in parallel with ticket 1: %v", events)
+	}
+	if position["start 2"] < position["end 1"] {
+		t.Errorf("ticket 2 started before its dependency finished: %v", events)
+	}
+}
+
+func TestRunHeadlessBlockedDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	tickets := `## Ticket 1: Fails
+## Ticket 2: After one
+Depends: 1
+## Ticket 3: After two
+Depends: 2
+## Ticket 4: Unknown dependency
+Depends: 9
+`
+	writeTestProject(t, tmpDir, "demo", tickets)
+	chdir(t, tmpDir)
+	if err := os.WriteFile("agent.sh", []byte(parallelAgent), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("FAIL_FIRST", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-concurrency", "2"}, &out); code != 1 {
+		t.Fatalf("runHeadless() = %d, want 1\n%s", code, out.String())
+	}
+
+	for _, want := range []string{
+		"🚫 Ticket 2 not started: blocked by failed ticket 1",
+		"🚫 Ticket 3 not started: blocked by failed ticket 2",
+		"🚫 Ticket 4 not started: depends on unknown ticket 9",
+		"Summary: 0 successful, 1 failed, 3 blocked, 0 unknown, 4 total",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "▶ Ticket 2") {
+		t.Errorf("blocked ticket 2 was started:\n%s", out.String())
+	}
+}
+
+func TestConfirmationKeys(t *testing.T) {
+	m := initialModel()
+	m.State = StateConfirmation
+
+	press := func(key string) {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = next.(Model)
+	}
+
+	press("e")
+	if m.ExitPolicy != ExitPolicySuccess {
+		t.Errorf("ExitPolicy after e = %q, want %q", m.ExitPolicy, ExitPolicySuccess)
+	}
+	press("t")
+	if m.Timeout != 10*time.Minute {
+		t.Errorf("Timeout after t = %v, want 10m", m.Timeout)
+	}
+	press("c")
+	if m.Concurrency != 2 {
+		t.Errorf("Concurrency after c = %d, want 2", m.Concurrency)
+	}
+	press("r")
+	if m.Retries != 1 {
+		t.Errorf("Retries after r = %d, want 1", m.Retries)
+	}
+	press("w")
+	press("a")
+	if !m.UseWorktrees || !m.AutoCommit {
+		t.Errorf("UseWorktrees, AutoCommit after w and a = %v, %v, want both on", m.UseWorktrees, m.AutoCommit)
+	}
+	if m.State != StateConfirmation {
+		t.Errorf("State = %v, settings keys must not start the run", m.State)
+	}
+
+	// Parallel agents cannot commit in the shared checkout
+	press("w")
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	if m.ConfirmReady || !strings.Contains(m.View(), "needs worktrees") {
+		t.Errorf("ConfirmReady = %v after Enter, want the run refused:\n%s", m.ConfirmReady, m.View())
+	}
+}
+
+func TestTicketSelectionKeys(t *testing.T) {
+	m := initialModel()
+	m.Tickets = []Ticket{
+		{Number: 1, Description: "One"},
+		{Number: 2, Description: "Two", Dependencies: []int{1}},
+		{Number: 3, Description: "Three", Dependencies: []int{2}},
+		{Number: 4, Description: "Four"},
+	}
+	m.PreviousRun = &runState{Tickets: []Ticket{
+		{Number: 1, Status: StatusSucceeded},
+		{Number: 3, Status: StatusTimedOut},
+	}}
+	next, _ := m.Update(proceedToTicketSelectionMsg{})
+	m = next.(Model)
+	if m.State != StateTicketSelection {
+		t.Fatalf("State = %v, want StateTicketSelection", m.State)
+	}
+
+	press := func(keys ...string) {
+		for _, key := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+			if key == " " {
+				msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(key)}
+			}
+			next, _ := m.Update(msg)
+			m = next.(Model)
+		}
+	}
+	numbers := func() []int {
+		selected := []int{}
+		for _, ticket := range m.selectedTickets() {
+			selected = append(selected, ticket.Number)
+		}
+		return selected
+	}
+
+	press("f")
+	if got := numbers(); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("selection after f = %v, want [3]", got)
+	}
+	press("A")
+	if got := numbers(); !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("selection after A = %v, want all", got)
+	}
+	press("A", "j", "v", "j", "j", "v")
+	if got := numbers(); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("selection after range = %v, want [2 3 4]", got)
+	}
+	// Move ticket 4 to the top and drop ticket 3
+	press("K", "K", "K", "j", "j", "j", " ")
+	if got := numbers(); !reflect.DeepEqual(got, []int{4, 2}) {
+		t.Errorf("selection after reorder = %v, want [4 2]", got)
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	if m.State != StateAgentSelection {
+		t.Fatalf("State after enter = %v, want StateAgentSelection", m.State)
+	}
+	if len(m.Tickets) != 2 || m.Tickets[0].Number != 4 || m.Tickets[1].Number != 2 {
+		t.Fatalf("Tickets = %+v, want 4 then 2", m.Tickets)
+	}
+	if len(m.Tickets[1].Dependencies) != 0 {
+		t.Errorf("ticket 2 dependencies = %v, the dependency on unselected ticket 1 should be dropped", m.Tickets[1].Dependencies)
+	}
+}
+
+func TestParseTicketSelection(t *testing.T) {
+	tickets := []Ticket{{Number: 10}, {Number: 20}, {Number: 30}, {Number: 5}}
+
+	tests := []struct {
+		spec     string
+		expected []int
+		err      string
+	}{
+		{spec: "20", expected: []int{20}},
+		{spec: "30, 10", expected: []int{30, 10}},
+		{spec: "1-20", expected: []int{5, 10, 20}},
+		{spec: "30,5-30", expected: []int{30, 5, 10, 20}},
+		{spec: "7", err: "no ticket 7"},
+		{spec: "ten", err: `invalid ticket "ten"`},
+		{spec: "20-10", err: `invalid ticket range "20-10"`},
+		{spec: "11-19", err: `no tickets in range "11-19"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseTicketSelection(tt.spec, tickets)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("parseTicketSelection(%q) error = %v, want %q", tt.spec, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTicketSelection(%q) error = %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseTicketSelection(%q) = %v, want %v", tt.spec, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunHeadlessTicketSelection(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n## Ticket 2: Second\nDepends: 1\n## Ticket 3: Third\n")
+	chdir(t, tmpDir)
+	if err := os.WriteFile("agent.sh", []byte(`echo success > "$KILL_FILE"`), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-tickets", "3,2"}, &out)
+	if code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	third := strings.Index(out.String(), "▶ Ticket 3")
+	second := strings.Index(out.String(), "▶ Ticket 2")
+	if third < 0 || second < third {
+		t.Errorf("want ticket 3 started before ticket 2:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "▶ Ticket 1") {
+		t.Errorf("unselected ticket 1 was started:\n%s", out.String())
+	}
+
+	// The saved run lists ticket 3 first; mark it failed and rerun only that one
+	state, err := loadRunState("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Tickets[0].Status = StatusFailed
+	content, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(runStatePath("demo"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	code = runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-only-failed"}, &out)
+	if code != 0 {
+		t.Fatalf("runHeadless(-only-failed) = %d, want 0\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Running 1 tickets") || !strings.Contains(out.String(), "▶ Ticket 3") {
+		t.Errorf("want only ticket 3 to run:\n%s", out.String())
+	}
+}
+
+func TestOutputPane(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "ticket-1.log")
+	lines := []string{}
+	for i := 1; i <= 50; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\nloading 10%\rloading 100%\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel()
+	m.State = StateRunning
+	m.Tickets = []Ticket{{Number: 1, Description: "One", LogPath: logPath}, {Number: 2, Description: "Two"}}
+	update := func(msg tea.Msg) {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+
+	update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	if m.Output.Width != 98 || m.Output.Height != 18 {
+		t.Errorf("output pane size = %dx%d, want 98x18", m.Output.Width, m.Output.Height)
+	}
+
+	// The pane follows the end of the log, showing redrawn lines once
+	update(time.Now())
+	view := m.View()
+	if !strings.Contains(view, "Ticket 1 output (following)") || !strings.Contains(view, "loading 100%") || strings.Contains(view, "loading 10%") {
+		t.Errorf("output pane does not show the end of the log:\n%s", view)
+	}
+
+	// Scrolling back stops following, new output does not move the pane
+	update(tea.KeyMsg{Type: tea.KeyPgUp})
+	if m.FollowOutput {
+		t.Error("FollowOutput after PgUp = true, want false")
+	}
+	offset := m.Output.YOffset
+	if err := os.WriteFile(logPath, []byte(strings.Join(append(lines, lines...), "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	update(time.Now())
+	if m.Output.YOffset != offset {
+		t.Errorf("YOffset after new output = %d, want %d", m.Output.YOffset, offset)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	if !m.FollowOutput || !m.Output.AtBottom() {
+		t.Error("G does not jump to the end and follow the output")
+	}
+
+	// The pane shows the ticket under the cursor and can be hidden
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	if view := m.View(); !strings.Contains(view, "Ticket 2 output") || !strings.Contains(view, "(not started)") {
+		t.Errorf("output pane does not show ticket 2:\n%s", view)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if strings.Contains(m.View(), "Ticket 2 output") {
+		t.Error("output pane still shown after o")
+	}
+}
+
+func TestTicketListWindow(t *testing.T) {
+	m := initialModel()
+	m.State = StateRunning
+	for i := 1; i <= 100; i++ {
+		m.Tickets = append(m.Tickets, Ticket{
+			Number:      i,
+			Description: fmt.Sprintf("Ticket with a description far too long for a narrow terminal %d", i),
+			Body:        strings.Repeat("- a line of the ticket body\n", 20),
+		})
+	}
+	m.Cursor = 79
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 60, Height: 40})
+	m = next.(Model)
+
+	view := m.View()
+	height := 0
+	for _, line := range strings.Split(view, "\n") {
+		height += max((lipgloss.Width(line)+59)/60, 1)
+	}
+	if height > 40 {
+		t.Errorf("view takes %d lines, want at most 40:\n%s", height, view)
+	}
+	for _, want := range []string{"→ ⏳ Ticket 80: Ticket with a description", "…", "↑ ", "↓ ", "Ticket 80 details:", "more lines"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+	for _, line := range strings.Split(view, "\n") {
+		if strings.Contains(line, "Ticket 1:") || strings.Contains(line, "Ticket 100:") {
+			t.Errorf("view shows a ticket far from the cursor: %q", line)
+		}
+		if strings.Contains(line, "Ticket ") && lipgloss.Width(line) > 60 {
+			t.Errorf("line is wider than the terminal: %q", line)
+		}
+	}
+
+	// The list scrolls with the cursor and details can be hidden
+	m.Cursor = 0
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m = next.(Model)
+	view = m.View()
+	if !strings.Contains(view, "Ticket 1:") || strings.Contains(view, "↑ ") || strings.Contains(view, "details:") {
+		t.Errorf("want the top of the list without details:\n%s", view)
+	}
+}
+
+func TestRunHeadlessUsageErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	chdir(t, tmpDir)
+
+	var out strings.Builder
+	if code := runHeadless([]string{}, &out); code != 2 {
+		t.Errorf("runHeadless() without -project = %d, want 2", code)
+	}
+	if code := runHeadless([]string{"-project", "missing"}, &out); code != 2 {
+		t.Errorf("runHeadless() with missing project = %d, want 2", code)
+	}
+	if code := runHeadless([]string{"-project", "missing", "-on-clean-exit", "maybe"}, &out); code != 2 {
+		t.Errorf("runHeadless() with invalid exit policy = %d, want 2", code)
+	}
+	if code := runHeadless([]string{"-project", "missing", "-agent", "x", "-profile", "y"}, &out); code != 2 {
+		t.Errorf("runHeadless() with -agent and -profile = %d, want 2", code)
+	}
+	if code := runHeadless([]string{"-project", "missing", "-profile", "nobody"}, &out); code != 2 {
+		t.Errorf("runHeadless() with unknown profile = %d, want 2", code)
+	}
+	if code := runHeadless([]string{"-project", "missing", "-agent", "'unterminated"}, &out); code != 2 {
+		t.Errorf("runHeadless() with invalid agent command = %d, want 2", code)
+	}
+	if code := runHeadless([]string{"-project", "missing", "-resume", "-only-failed"}, &out); code != 2 {
+		t.Errorf("runHeadless() with -resume and -only-failed = %d, want 2", code)
+	}
+	if code := runHeadless([]string{"-project", "missing", "-commit", "-concurrency", "2"}, &out); code != 2 {
+		t.Errorf("runHeadless() with -commit and -concurrency 2 = %d, want 2", code)
+	}
+}
+
+// gitInit turns the current directory into a git repository with one commit.
+func gitInit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func TestWorktreeBranch(t *testing.T) {
+	tests := []struct {
+		ticket   Ticket
+		expected string
+	}{
+		{Ticket{Number: 3, Description: "Add Login Page"}, "ticket-3-add-login-page"},
+		{Ticket{Number: 4, Description: "Fix: crash (on startup)!"}, "ticket-4-fix-crash-on-startup"},
+		{Ticket{Number: 5, Description: "   "}, "ticket-5"},
+		{Ticket{Number: 6, Description: strings.Repeat("word ", 20)}, "ticket-6-word-word-word-word-word-word-word-word"},
+	}
+	for _, tt := range tests {
+		if got := worktreeBranch(tt.ticket); got != tt.expected {
+			t.Errorf("worktreeBranch(%q) = %q, want %q", tt.ticket.Description, got, tt.expected)
+		}
+	}
+}
+
+func TestRunHeadlessWorktrees(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n## Ticket 2: Second\n")
+	chdir(t, tmpDir)
+
+	// The agent leaves its work and its kill file in its working directory
+	agent := "#!/bin/sh\npwd > work.txt\necho success > \"$KILL_FILE\"\n"
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitInit(t)
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "./agent.sh", "-delay", "0", "-worktrees"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+
+	if _, err := os.Stat("work.txt"); err == nil {
+		t.Error("agent ran in the main checkout instead of a worktree")
+	}
+	for _, branch := range []string{"ticket-1-first", "ticket-2-second"} {
+		if err := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Run(); err != nil {
+			t.Errorf("branch %s was not created", branch)
+		}
+		if !strings.Contains(out.String(), "branch: "+branch) {
+			t.Errorf("output does not mention branch %s:\n%s", branch, out.String())
+		}
+	}
+
+	worktrees, err := filepath.Glob("runs/demo/*/worktrees/ticket-*/work.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worktrees) != 2 {
+		t.Errorf("found work in %d worktrees, want 2", len(worktrees))
+	}
+}
+
+func TestRunHeadlessWorktreeDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: Write the file\n## Ticket 2: Read the file\nDepends: 1\n")
+	chdir(t, tmpDir)
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+
+	// The second ticket only succeeds if it sees the first one's commit
+	agent := `if printf '%s\n' "$1" | grep -q 'work on ticket 1:'; then echo one > one.txt; echo success > "$KILL_FILE"; elif test -f one.txt; then echo success > "$KILL_FILE"; else echo failure > "$KILL_FILE"; fi`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitInit(t)
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-worktrees", "-commit"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	if strings.Contains(out.String(), "⚠️") {
+		t.Errorf("output warns although the tickets commit their work:\n%s", out.String())
+	}
+	if err := exec.Command("git", "merge-base", "--is-ancestor", "ticket-1-write-the-file", "ticket-2-read-the-file").Run(); err != nil {
+		t.Errorf("branch of ticket 2 does not start from ticket 1: %v", err)
+	}
+}
+
+func TestRunHeadlessCommit(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: Add notes\n## Ticket 2: Nothing to do\n")
+	chdir(t, tmpDir)
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+
+	// Only the first ticket changes a file
+	agent := `printf '%s\n' "$1" | grep -q 'work on ticket 1:' && echo notes > notes.txt; echo success > "$KILL_FILE"`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitInit(t)
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-commit"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+
+	log, err := exec.Command("git", "log", "--format=%s", "--name-only", "-1").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(log)), "Ticket 1: Add notes\n\nnotes.txt"; got != want {
+		t.Errorf("last commit = %q, want %q", got, want)
+	}
+	if !strings.Contains(out.String(), "1 file changed, 1 insertion(+)") {
+		t.Errorf("output missing diffstat:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "without changes (no-op)") {
+		t.Errorf("output does not flag ticket 2 as no-op:\n%s", out.String())
+	}
+
+	state, err := loadRunState("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Tickets[0].CommitSHA == "" || state.Tickets[0].NoOp {
+		t.Errorf("ticket 1 = %+v, want a commit", state.Tickets[0])
+	}
+	if state.Tickets[1].CommitSHA != "" || !state.Tickets[1].NoOp {
+		t.Errorf("ticket 2 = %+v, want a no-op", state.Tickets[1])
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+		wantErr  bool
+	}{
+		{"claude --dangerously-skip-permissions", []string{"claude", "--dangerously-skip-permissions"}, false},
+		{`aider --message-file "my prompt.md"`, []string{"aider", "--message-file", "my prompt.md"}, false},
+		{`wrapper --model='gpt 4' -x`, []string{"wrapper", "--model=gpt 4", "-x"}, false},
+		{`run a\ b "say \"hi\"" ''`, []string{"run", "a b", `say "hi"`, ""}, false},
+		{"  spaced\targs  ", []string{"spaced", "args"}, false},
+		{`broken "quote`, nil, true},
+		{`trailing \`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitCommand(%q) error = %v, wantErr %v", tt.command, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.expected) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadAgentProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "agents.json")
+
+	profiles, err := loadAgentProfiles(path)
+	if err != nil || len(profiles) != 1 || profiles[0].Name != "claude" {
+		t.Fatalf("loadAgentProfiles() without config = %v, %v, want the built-in claude profile", profiles, err)
+	}
+
+	config := `{"profiles": [
+		{"name": "codex", "binary": "codex", "args": ["exec", "--full-auto"]},
+		{"name": "claude", "binary": "claude", "args": ["--model", "opus"], "env": {"CLAUDE_DEBUG": "1"}}
+	]}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err = loadAgentProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].String() != "claude --model opus" || profiles[1].String() != "codex exec --full-auto" {
+		t.Errorf("loadAgentProfiles() = %v, want the configured claude profile first, then codex", profiles)
+	}
+
+	invalid := []string{
+		`{"profiles": [{"name": "x"}]}`,
+		`{"profiles": [{"name": "x", "binary": "x"}, {"name": "x", "binary": "y"}]}`,
+		`{"profiles": [{"name": "x", "binary": "x", "prompt": "carrier-pigeon"}]}`,
+		`{"profiles": [{"name": "x", "binary": "x", "rate_limit_patterns": ["("]}]}`,
+		`not json`,
+	}
+	for _, config := range invalid {
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadAgentProfiles(path); err == nil {
+			t.Errorf("loadAgentProfiles(%s) succeeded, want an error", config)
+		}
+	}
+}
+
+func TestAgentSelection(t *testing.T) {
+	m := initialModel()
+	m.Profiles = []AgentProfile{defaultAgent, {Name: "codex", Binary: "codex"}}
+	m.State = StateAgentSelection
+
+	press := func(msg tea.KeyMsg) {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+
+	// The custom command entry follows the last profile
+	press(tea.KeyMsg{Type: tea.KeyUp})
+	if m.SelectedAgent != 2 {
+		t.Fatalf("SelectedAgent after up = %d, want 2", m.SelectedAgent)
+	}
+	press(tea.KeyMsg{Type: tea.KeyUp})
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.State != StateConfirmation || m.Agent.Name != "codex" {
+		t.Errorf("State, Agent = %v, %q, want confirmation with codex", m.State, m.Agent.Name)
+	}
+}
+
+func TestRunHeadlessProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n")
+	chdir(t, tmpDir)
+
+	// The profile passes an argument containing spaces, sets an environment
+	// variable and runs the agent in a subdirectory
+	agent := `printf '%s|%s|%s\n' "$1" "$GREETING" "$(basename "$PWD")" > args.txt; echo success > "$KILL_FILE"`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("work", 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"profiles": [{"name": "test", "binary": "sh", "args": ["../agent.sh", "two words"], "env": {"GREETING": "hello"}, "dir": "work"}]}`
+	if err := os.WriteFile("agents.json", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-profile", "test", "-delay", "0"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	content, err := os.ReadFile("work/args.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "two words|hello|work\n"; got != want {
+		t.Errorf("agent saw %q, want %q", got, want)
+	}
+}
+
+func TestRunHeadlessPromptModes(t *testing.T) {
+	tests := []struct {
+		mode  string
+		agent string
+		args  []string
+	}{
+		{"argv", `printf '%s' "$1" > prompt.txt`, nil},
+		{"stdin", `cat > prompt.txt`, nil},
+		{"file", `cp "$1" prompt.txt; echo "$PROMPT_FILE" > path.txt`, []string{"{prompt_file}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\nA long body\n")
+			chdir(t, tmpDir)
+
+			if err := os.WriteFile("agent.sh", []byte(tt.agent+"\necho success > \"$KILL_FILE\"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			command := strings.Join(append([]string{"sh agent.sh"}, tt.args...), " ")
+
+			var out strings.Builder
+			if code := runHeadless([]string{"-project", "demo", "-agent", command, "-prompt", tt.mode, "-delay", "0"}, &out); code != 0 {
+				t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+			}
+
+			prompt, err := os.ReadFile("prompt.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(prompt), "Please work on ticket 1: First.") || !strings.Contains(string(prompt), "A long body") {
+				t.Errorf("agent received prompt %q", prompt)
+			}
+
+			if tt.mode == "file" {
+				path, err := os.ReadFile("path.txt")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := os.Stat(strings.TrimSpace(string(path))); !os.IsNotExist(err) {
+					t.Errorf("prompt file %s was not removed", strings.TrimSpace(string(path)))
+				}
+			}
+		})
+	}
+}
+
+func TestBuildPromptTemplate(t *testing.T) {
+	m := initialModel().selectProject("demo")
+	m.RunDir = "/runs/demo/earlier"
+	standardPrompt := `Project {{.Project}}, ticket {{.TicketNumber}}: {{.Description}}
+{{.Body}}
+Spec: {{.SpecificationPath}}, tickets: {{.TicketsPath}}
+{{if .PreviousTicket}}Ticket {{.PreviousTicket}} ended with {{.PreviousResult}}.{{end}}
+Write {{.KillFile}} when done.`
+
+	ticket := Ticket{Number: 2, Description: "Second", Body: "Do the second thing", Attempts: 1}
+	previous := Ticket{Number: 1, Status: StatusFailed}
+	prompt, err := m.buildPrompt(standardPrompt, ticket, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Project demo, ticket 2: Second
+Do the second thing
+Spec: input/demo/specification.md, tickets: input/demo/tickets.md
+Ticket 1 ended with failure.
+Write /runs/demo/earlier/killmenow-2-1.md when done.`
+	if prompt != expected {
+		t.Errorf("buildPrompt() = %q, want %q", prompt, expected)
+	}
+
+	// Templates can keep the default wording
+	prompt, err = m.buildPrompt("Be careful. {{.Instructions}}", ticket, Ticket{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(prompt, "Be careful. Please use the documentation in the input/demo folder") {
+		t.Errorf("buildPrompt() = %q, want the default instructions", prompt)
+	}
+
+	if _, err := m.buildPrompt("{{.Unknown}}", ticket, previous); err == nil {
+		t.Error("buildPrompt() with an unknown variable succeeded, want an error")
+	}
+}
+
+func TestCheckFilesPromptTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n")
+	chdir(t, tmpDir)
+
+	if result := checkFiles("demo"); result.PromptError != nil {
+		t.Errorf("checkFiles() PromptError = %v for a plain prompt", result.PromptError)
+	}
+
+	for _, prompt := range []string{"Work on {{.TicketNumber", "Work on {{.Ticket}}"} {
+		if err := os.WriteFile(filepath.Join(projectDir, "standard-prompt.md"), []byte(prompt), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if result := checkFiles("demo"); result.PromptError == nil {
+			t.Errorf("checkFiles() accepted template %q", prompt)
+		}
+
+		var out strings.Builder
+		if code := runHeadless([]string{"-project", "demo", "-agent", "true"}, &out); code != 2 {
+			t.Errorf("runHeadless() with template %q = %d, want 2", prompt, code)
+		}
+	}
+}
+
+func TestParseTicketsMetadata(t *testing.T) {
+	tmpfile := t.TempDir() + "/tickets.md"
+	content := `## Ticket 1: Key value lines
+Agent: codex
+Dir: services/api
+Labels: backend, urgent
+Retries: 2
+Timeout: 15m
+
+Build the API.
+Note: this line is part of the body.
+## Ticket 2: Front matter
+---
+profile: aider
+workdir: web
+labels: [frontend, "ui"]
+depends: [1]
+retries: many
+---
+Build the UI.
+## Ticket 3: No metadata
+Summary: not a metadata key, so the body starts here.
+## Ticket 4: Horizontal rule
+
+---
+Important body text
+- bullet
+## Ticket 5: Closed later
+---
+Agent: codex
+Retries: 0
+---
+## Ticket 6: Text between rules
+---
+Intro text
+---`
+	if err := os.WriteFile(tmpfile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tickets, err := parseTickets(tmpfile)
+	if err != nil {
+		t.Fatalf("parseTickets() error = %v", err)
+	}
+
+	expected := []Ticket{
+		{Agent: "codex", Dir: "services/api", Labels: []string{"backend", "urgent"}, Retries: intPtr(2), Timeout: 15 * time.Minute,
+			Body: "Build the API.\nNote: this line is part of the body."},
+		{Agent: "aider", Dir: "web", Labels: []string{"frontend", "ui"}, Dependencies: []int{1},
+			Body: "Build the UI."},
+		{Body: "Summary: not a metadata key, so the body starts here."},
+		{Body: "---\nImportant body text\n- bullet"},
+		{Agent: "codex", Retries: intPtr(0)},
+		{Body: "---\nIntro text\n---"},
+	}
+	for i, want := range expected {
+		got := tickets[i]
+		if got.Agent != want.Agent || got.Dir != want.Dir || !reflect.DeepEqual(got.Retries, want.Retries) || got.Timeout != want.Timeout {
+			t.Errorf("ticket[%d] agent, dir, retries, timeout = %q, %q, %s, %v, want %q, %q, %s, %v",
+				i, got.Agent, got.Dir, formatRetries(got.Retries), got.Timeout, want.Agent, want.Dir, formatRetries(want.Retries), want.Timeout)
+		}
+		if fmt.Sprint(got.Labels) != fmt.Sprint(want.Labels) || fmt.Sprint(got.Dependencies) != fmt.Sprint(want.Dependencies) {
+			t.Errorf("ticket[%d] labels, dependencies = %v, %v, want %v, %v", i, got.Labels, got.Dependencies, want.Labels, want.Dependencies)
+		}
+		if got.Body != want.Body {
+			t.Errorf("ticket[%d].Body = %q, want %q", i, got.Body, want.Body)
+		}
+	}
+}
+
+func TestRunHeadlessTicketMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	tickets := `## Ticket 1: Flaky
+Retries: 1
+## Ticket 2: Other agent
+Agent: other
+Dir: sub
+## Ticket 3: Unknown agent
+Agent: missing
+`
+	writeTestProject(t, tmpDir, "demo", tickets)
+	chdir(t, tmpDir)
+
+	// The default agent fails on its first attempt only
+	flaky := `if [ -f attempted ]; then echo success > "$KILL_FILE"; else touch attempted; echo failure > "$KILL_FILE"; fi`
+	if err := os.WriteFile("agent.sh", []byte(flaky), 0755); err != nil {
+		t.Fatal(err)
+	}
+	other := `basename "$PWD" > ran-other.txt; echo success > "$KILL_FILE"`
+	if err := os.WriteFile("other.sh", []byte(other), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"profiles": [{"name": "other", "binary": "sh", "args": ["../other.sh"]}]}`
+	if err := os.WriteFile("agents.json", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out); code != 1 {
+		t.Fatalf("runHeadless() = %d, want 1\n%s", code, out.String())
+	}
+
+	for _, want := range []string{
+		"Retrying ticket 1 (attempt 2 of 2) after: agent reported failure",
+		"✅ Ticket 1 completed",
+		"✅ Ticket 2 completed",
+		`❌ Ticket 3 failed after`,
+		`unknown agent profile "missing"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if content, err := os.ReadFile("sub/ran-other.txt"); err != nil || string(content) != "sub\n" {
+		t.Errorf("ticket 2 did not run the other agent in sub/: %q, %v", content, err)
+	}
+}
+
+func TestRunHeadlessRetries(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: Flaky\n")
+	chdir(t, tmpDir)
+
+	// The first attempt fails with some output, the second records its prompt
+	agent := `if [ -f attempted ]; then printf '%s\n' "$1" > retry-prompt.txt; echo success > "$KILL_FILE"; else touch attempted; echo "compile error in main.go"; echo failure > "$KILL_FILE"; fi`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-retries", "1"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Retrying ticket 1 (attempt 2 of 2) after: agent reported failure") {
+		t.Errorf("output does not report the retry:\n%s", out.String())
+	}
+
+	prompt, err := os.ReadFile("retry-prompt.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"This is attempt 2 of 2. The previous attempt failed: agent reported failure.",
+		"compile error in main.go",
+	} {
+		if !strings.Contains(string(prompt), want) {
+			t.Errorf("retry prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	// Every attempt keeps its own log
+	for _, name := range []string{"ticket-1.log", "ticket-1-attempt-2.log"} {
+		if matches, _ := filepath.Glob(filepath.Join("runs", "demo", "*", name)); len(matches) != 1 {
+			t.Errorf("found %d logs named %s", len(matches), name)
+		}
+	}
+}
+
+func TestTicketRetries(t *testing.T) {
+	m := initialModel()
+	m.Retries = 2
+	tests := []struct {
+		name    string
+		retries *int
+		want    int
+	}{
+		{name: "Run value", retries: nil, want: 2},
+		{name: "Ticket value", retries: intPtr(3), want: 3},
+		{name: "Ticket turns retries off", retries: intPtr(0), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.ticketRetries(Ticket{Retries: tt.retries}); got != tt.want {
+				t.Errorf("ticketRetries() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "ticket-1.log")
+	verifyLogPath := filepath.Join(dir, "ticket-1.verify.log")
+	agentOutput := strings.Repeat("early agent output\n", 1000) + "edited main.go"
+	if err := os.WriteFile(logPath, []byte(agentOutput), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(verifyLogPath, []byte("$ go test ./...\nFAIL main_test.go"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := initialModel()
+	m.Retries = 1
+	ticket := Ticket{Number: 1, Attempts: 2, History: []Attempt{{
+		Number:        1,
+		Status:        StatusVerifyFailed,
+		FailureReason: "verification failed: go test ./...",
+		LogPath:       logPath,
+		VerifyLogPath: verifyLogPath,
+	}}}
+
+	context := m.retryContext(ticket)
+	for _, want := range []string{
+		"This is attempt 2 of 2. The previous attempt failed: verification failed: go test ./....",
+		"The end of its verification output was:\n\n$ go test ./...\nFAIL main_test.go",
+		"The end of its agent output was:",
+		"edited main.go",
+	} {
+		if !strings.Contains(context, want) {
+			t.Errorf("retryContext() missing %q:\n%s", want, context)
+		}
+	}
+	if len(context) > retryLogTailBytes+retryVerifyTailBytes+500 {
+		t.Errorf("retryContext() is %d bytes, want the agent log cut to its tail", len(context))
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		profile  AgentProfile
+		output   string
+		exitCode int
+		want     bool
+	}{
+		{defaultAgent, "Error: 429 Too Many Requests", 1, true},
+		{defaultAgent, "API Error: overloaded_error", 1, true},
+		{defaultAgent, "Claude AI usage limit reached|1767225600", 1, true},
+		{defaultAgent, "compile error in main.go", 1, false},
+		{defaultAgent, "implemented rate limit middleware", 0, false},
+		{AgentProfile{RateLimitExitCodes: []int{0}}, "", 0, true},
+		{AgentProfile{RateLimitPatterns: []string{`slow down`}}, "rate limit exceeded", 1, false},
+		{AgentProfile{RateLimitPatterns: []string{`slow down`}}, "please slow down", 1, true},
+		{AgentProfile{RateLimitExitCodes: []int{75}}, "", 75, true},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.rateLimited(tt.output, tt.exitCode); got != tt.want {
+			t.Errorf("rateLimited(%q, %d) = %v, want %v", tt.output, tt.exitCode, got, tt.want)
+		}
+	}
+}
+
+func TestParseRateLimitWait(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		output string
+		want   time.Duration
+		ok     bool
+	}{
+		{"HTTP 429\nRetry-After: 30", 30 * time.Second, true},
+		{"Rate limited, please try again in 5 minutes.", 5 * time.Minute, true},
+		{"Too many requests. Retry in 2h", 2 * time.Hour, true},
+		{fmt.Sprintf("Claude AI usage limit reached|%d", now.Add(90*time.Minute).Unix()), 90 * time.Minute, true},
+		{"Quota exceeded, resets at 2026-01-01T12:10:00Z.", 10 * time.Minute, true},
+		{"Retry-After: 60\nRetry-After: 5", 5 * time.Second, true},
+		{"rate limit exceeded", 0, false},
+		{"usage limit reached|1000000000", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRateLimitWait(tt.output, now)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseRateLimitWait(%q) = %v, %v, want %v, %v", tt.output, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if rateLimitBackoff(1) != 30*time.Second || rateLimitBackoff(3) != 2*time.Minute || rateLimitBackoff(20) != 10*time.Minute {
+		t.Errorf("rateLimitBackoff() = %v, %v, %v, want 30s, 2m, 10m", rateLimitBackoff(1), rateLimitBackoff(3), rateLimitBackoff(20))
+	}
+}
+
+func TestRunHeadlessRateLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: Limited\n")
+	chdir(t, tmpDir)
+
+	// The first attempt is rate limited, the second succeeds
+	agent := `if [ -f attempted ]; then echo success > "$KILL_FILE"; else touch attempted; echo "Error: rate limit exceeded"; echo "Retry-After: 1"; exit 1; fi`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without any retries configured the ticket must still succeed
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		"Waiting 1 second before next agent: rate limited for 1 second, retrying ticket 1",
+		"Retrying ticket 1 after: rate limited for 1 second",
+		"✅ Ticket 1 completed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunHeadlessStaleKillFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n")
+	chdir(t, tmpDir)
+
+	// Left over from a crashed run, claiming success
+	if err := os.WriteFile("killmenow.md", []byte("success"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The agent takes a moment and reports where it was told to write
+	agent := `sleep 1; printf '%s\n' "$1" | grep -o "create a file named '[^']*'" > prompt-file.txt; echo "$KILL_FILE" > env-file.txt; echo failure > "$KILL_FILE"`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out); code != 1 {
+		t.Fatalf("runHeadless() = %d, want 1 as the stale file must not count\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Removing stale kill file killmenow.md") {
+		t.Errorf("output does not report the stale kill file:\n%s", out.String())
+	}
+	if _, err := os.Stat("killmenow.md"); !os.IsNotExist(err) {
+		t.Error("stale kill file was not removed")
+	}
+
+	envFile, err := os.ReadFile("env-file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	killFile := strings.TrimSpace(string(envFile))
+	if !filepath.IsAbs(killFile) || !strings.HasSuffix(killFile, "killmenow-1-1.md") || !strings.Contains(killFile, filepath.Join("runs", "demo")) {
+		t.Errorf("%s = %q, want an absolute per-attempt path in the run directory", "KILL_FILE", killFile)
+	}
+	promptFile, err := os.ReadFile("prompt-file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(promptFile)), "create a file named '"+killFile+"'"; got != want {
+		t.Errorf("prompt asks for %q, want %q", got, want)
+	}
+}
+
+func TestParseCompletionReport(t *testing.T) {
+	tests := []struct {
+		content string
+		status  string
+		summary string
+		wantErr bool
+		failed  bool
+	}{
+		{"success", "success", "", false, false},
+		{"Success.\n", "success", "", false, false},
+		{"failure: tests do not compile", "failure", "tests do not compile", false, true},
+		{"unsuccessful", "", "", true, true},
+		{"I think it was a success", "", "", true, true},
+		{`{"status": "success", "summary": "Added login", "files_changed": ["login.go"], "follow_ups": ["Add logout"]}`, "success", "Added login", false, false},
+		{`{"status": "PARTIAL", "summary": "Half done"}`, "partial", "Half done", false, true},
+		{`{"status": "blocked"}`, "blocked", "", false, true},
+		{`{"status": "done"}`, "", "", true, true},
+		{`{"status": "success"`, "", "", true, true},
+	}
+	for _, tt := range tests {
+		report, err := parseCompletionReport(tt.content)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCompletionReport(%q) error = %v, wantErr %v", tt.content, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if report.Status != tt.status || report.Summary != tt.summary {
+			t.Errorf("parseCompletionReport(%q) = %q, %q, want %q, %q", tt.content, report.Status, report.Summary, tt.status, tt.summary)
+		}
+		if (report.err() != nil) != tt.failed {
+			t.Errorf("parseCompletionReport(%q).err() = %v, want failed %v", tt.content, report.err(), tt.failed)
+		}
+	}
+}
+
+func TestRunHeadlessCompletionReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n## Ticket 2: Second\n")
+	chdir(t, tmpDir)
+
+	// The JSON report is written in two steps, the first read must not count
+	agent := `case "$1" in
+*"ticket 1:"*)
+	printf '{"status": "success", "summary": "Added the login page",' > "$KILL_FILE"
+	sleep 1
+	printf ' "files_changed": ["login.go", "login_test.go"], "follow_ups": ["Add a logout button"]}' >> "$KILL_FILE" ;;
+*) echo unsuccessful > "$KILL_FILE" ;;
+esac`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out); code != 1 {
+		t.Fatalf("runHeadless() = %d, want 1\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		"✅ Ticket 1 completed",
+		"summary: Added the login page",
+		"files changed: login.go, login_test.go",
+		"follow-up: Add a logout button",
+		`invalid completion report: unknown status "unsuccessful"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	state, err := loadRunState("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := state.Tickets[0].Report; report == nil || len(report.FilesChanged) != 2 {
+		t.Errorf("ticket 1 report = %+v, want it saved with the changed files", report)
+	}
+}
+
+func TestRunHeadlessFollowUpTickets(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := writeTestProject(t, tmpDir, "demo", "## Ticket 1: Login\n## Ticket 3: Signup\n")
+	chdir(t, tmpDir)
+
+	agent := `printf '%s\n' "$1" | grep -o 'work on ticket [0-9]*' >> worked.txt
+case "$1" in
+*"ticket 1:"*) echo '{"status": "success", "new_tickets": [{"description": "Logout", "body": "Add a logout button"}]}' > "$KILL_FILE" ;;
+*) echo success > "$KILL_FILE" ;;
+esac`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without approval the proposal is only reported
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Ticket 1 proposed a new ticket, not added without -approve-follow-ups: Logout") {
+		t.Errorf("output does not report the proposal:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := os.Remove("worked.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-approve-follow-ups", "-write-follow-ups"}, &out); code != 0 {
+		t.Fatalf("runHeadless() = %d, want 0\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Added ticket 4 proposed by ticket 1: Logout") {
+		t.Errorf("output does not report the added ticket:\n%s", out.String())
+	}
+	worked, err := os.ReadFile("worked.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(worked), "work on ticket 1\nwork on ticket 3\nwork on ticket 4\n"; got != want {
+		t.Errorf("agent worked on %q, want %q", got, want)
+	}
+
+	// The approved ticket is written back with the next free number
+	tickets, err := parseTickets(filepath.Join(projectDir, "tickets.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 3 || tickets[2].Number != 4 || tickets[2].Description != "Logout" || tickets[2].Body != "Add a logout button" {
+		t.Errorf("tickets.md now has %+v, want ticket 4 appended", tickets)
+	}
+}
+
+func TestApproveProposedTickets(t *testing.T) {
+	chdir(t, t.TempDir())
+	m := initialModel()
+	m.State = StateCompleted
+	m.Running = map[int]*runningAgent{}
+	m.Tickets = []Ticket{{Number: 1, Description: "First", Status: StatusSucceeded}}
+	m.ProposedTickets = []Ticket{
+		{Description: "Discarded", ProposedBy: 1},
+		{Description: "Approved", ProposedBy: 1},
+	}
+
+	press := func(key string) {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = next.(Model)
+	}
+
+	if !strings.Contains(m.View(), "Ticket 1 proposes a new ticket: Discarded") {
+		t.Errorf("View() does not ask for approval:\n%s", m.View())
+	}
+	press("n")
+	press("y")
+	if len(m.ProposedTickets) != 0 {
+		t.Errorf("ProposedTickets = %v, want none left", m.ProposedTickets)
+	}
+	if len(m.Tickets) != 2 || m.Tickets[1].Number != 2 || m.Tickets[1].Description != "Approved" {
+		t.Errorf("Tickets = %+v, want the approved ticket appended as ticket 2", m.Tickets)
+	}
+	if m.State != StateRunning || len(m.Running) != 1 {
+		t.Errorf("State = %v with %d running, want the completed run to continue", m.State, len(m.Running))
+	}
+}
+
+func TestApproveProposedTicketAfterSelection(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestProject(t, tmpDir, "demo", "## Ticket 1: First\n## Ticket 2: Second\n## Ticket 3: Third\n")
+	chdir(t, tmpDir)
+
+	// Only ticket 1 was selected, tickets 2 and 3 keep their numbers
+	m := initialModel().selectProject("demo")
+	m.State = StateCompleted
+	m.Running = map[int]*runningAgent{}
+	m.WriteBackTickets = true
+	m.Tickets = []Ticket{{Number: 1, Description: "First", Status: StatusSucceeded}}
+	m.ProposedTickets = []Ticket{{Description: "Follow-up", ProposedBy: 1}}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = next.(Model)
+	if len(m.Tickets) != 2 || m.Tickets[1].Number != 4 {
+		t.Fatalf("Tickets = %+v, want the follow-up appended as ticket 4", m.Tickets)
+	}
+	tickets, err := parseTickets(m.TicketsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 4 || tickets[3].Number != 4 || tickets[3].Description != "Follow-up" {
+		t.Errorf("tickets.md holds %+v, want the follow-up written as ticket 4", tickets)
+	}
+}
+
+func TestRunControlKeys(t *testing.T) {
+	chdir(t, t.TempDir())
+	m := initialModel()
+	m.State = StateRunning
+	m.RunDir = "runs/demo/now"
+	m.Tickets = []Ticket{
+		{Number: 1, Description: "Hanging", Attempts: 1, StartTime: time.Now()},
+		{Number: 2, Description: "Depends on the first", Dependencies: []int{1}},
+		{Number: 3, Description: "Independent"},
+	}
+	cmd := exec.Command("sleep", "30")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	m.Running = map[int]*runningAgent{0: {Cmd: cmd}}
+
+	press := func(key string) {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = next.(Model)
+	}
+
+	// Paused, skipping the running ticket starts nothing new
+	press("p")
+	if !m.Paused || !strings.Contains(m.View(), "Paused") {
+		t.Errorf("Paused = %v after p, want the run paused:\n%s", m.Paused, m.View())
+	}
+	press("s")
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("skipping did not kill the agent")
+	}
+	if m.Tickets[0].Status != StatusSkipped || len(m.Running) != 0 {
+		t.Errorf("Status = %q with %d running, want ticket 1 skipped and nothing started", m.Tickets[0].Status, len(m.Running))
+	}
+	if m.Tickets[1].Status != StatusBlocked || m.Tickets[1].FailureReason != "blocked by skipped ticket 1" {
+		t.Errorf("ticket 2 = %+v, want it blocked by the skipped ticket", m.Tickets[1])
+	}
+
+	// Aborting keeps the TUI open with a partial summary
+	press("x")
+	if m.State != StateCompleted || !m.Aborted {
+		t.Fatalf("State = %v, Aborted = %v after x, want an aborted run", m.State, m.Aborted)
+	}
+	view := m.View()
+	for _, want := range []string{"Run aborted!", "Ticket 3: Independent", "(not run)", "⏭️  Skipped: 1", "🚫 Blocked: 1", "⏳ Not run: 1"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q:\n%s", want, view)
+		}
+	}
+
+	// Retrying the skipped ticket resumes the run and unblocks its dependent
+	m.Cursor = 0
+	press("r")
+	if m.State != StateRunning || m.Aborted {
+		t.Errorf("State = %v, Aborted = %v after r, want the run resumed", m.State, m.Aborted)
+	}
+	if m.Tickets[0].finished() || m.Tickets[1].finished() || m.Running[0] == nil {
+		t.Errorf("tickets = %+v with %d running, want ticket 1 started again and ticket 2 pending", m.Tickets, len(m.Running))
+	}
+	if got := m.Tickets[0].History; len(got) != 1 || got[0].Status != StatusSkipped {
+		t.Errorf("History = %+v, want the skipped attempt", got)
+	}
+}
+
+func TestRetryDuringVerification(t *testing.T) {
+	chdir(t, t.TempDir())
+	m := initialModel()
+	m.State = StateRunning
+	m.RunDir = "runs/demo/now"
+	m.Tickets = []Ticket{{Number: 1, Description: "Verifying", Status: StatusRunning, Attempts: 1, StartTime: time.Now()}}
+	cmd := exec.Command("sleep", "30")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = cmd.Wait() }()
+	m.Running = map[int]*runningAgent{0: {Cmd: cmd, Verifying: true}}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(Model)
+	if m.Running[0] == nil || m.Running[0].Verifying {
+		t.Fatalf("Running = %+v after r, want a new attempt", m.Running)
+	}
+
+	// The old attempt's results must not complete the new one
+	next, _ = m.Update(ticketVerifiedMsg{ticket: 0, cmd: cmd, logPath: "old.verify.log"})
+	m = next.(Model)
+	next, _ = m.Update(ticketCommittedMsg{ticket: 0, cmd: cmd, sha: "abc123"})
+	m = next.(Model)
+	ticket := m.Tickets[0]
+	if ticket.Status != StatusRunning || ticket.VerifyLogPath != "" || ticket.CommitSHA != "" || m.Running[0] == nil {
+		t.Errorf("ticket = %+v with %d running, want the new attempt untouched", ticket, len(m.Running))
+	}
+}
+
+func TestManualRetryKeepsRetries(t *testing.T) {
+	chdir(t, t.TempDir())
+	m := initialModel()
+	m.State = StateRunning
+	m.RunDir = "runs/demo/now"
+	m.Retries = 1
+	m.Tickets = []Ticket{{Number: 1, Description: "Restarted", Status: StatusRunning, Attempts: 1, StartTime: time.Now()}}
+	cmd := exec.Command("sleep", "30")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = cmd.Wait() }()
+	m.Running = map[int]*runningAgent{0: {Cmd: cmd}}
+
+	// The restarted attempt fails and still gets its automatic retry
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(Model)
+	next, _ = m.Update(processCompleteMsg{ticket: 0, status: StatusFailed, err: errors.New("agent reported failure")})
+	m = next.(Model)
+	ticket := m.Tickets[0]
+	if ticket.Status != StatusPending || ticket.countedAttempts() != 1 {
+		t.Errorf("Status = %q after %d counted attempts, want the ticket queued for its retry", ticket.Status, ticket.countedAttempts())
+	}
+}
+
+func TestRunHeadlessVerification(t *testing.T) {
+	tmpDir := t.TempDir()
+	tickets := `## Ticket 1: Create the marker
+## Ticket 2: Claims too much
+Verify: grep -q two marker.txt
+`
+	projectDir := writeTestProject(t, tmpDir, "demo", tickets)
+	chdir(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(projectDir, "verify.txt"), []byte("# Every ticket needs the marker\ntest -f marker.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both agents report success, only the first one is right
+	agent := `echo one > marker.txt; echo success > "$KILL_FILE"`
+	if err := os.WriteFile("agent.sh", []byte(agent), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0"}, &out); code != 1 {
+		t.Fatalf("runHeadless() = %d, want 1\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		"✅ Ticket 1 completed",
+		"🧪 Ticket 2 failed verification after",
+		"verification failed: grep -q two marker.txt: exit status 1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	state, err := loadRunState("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Tickets[0].Status != StatusSucceeded || state.Tickets[1].Status != StatusVerifyFailed {
+		t.Errorf("tickets = %+v, want only ticket 2 to fail verification", state.Tickets)
+	}
+	verifyLog, err := os.ReadFile(state.Tickets[1].VerifyLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(verifyLog), "$ test -f marker.txt\n$ grep -q two marker.txt\nexit status 1\n"; got != want {
+		t.Errorf("verify log = %q, want %q", got, want)
+	}
+}
+
+func TestRunHeadlessVerificationTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := writeTestProject(t, tmpDir, "demo", "## Ticket 1: Verification hangs\n")
+	chdir(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(projectDir, "verify.txt"), []byte("sleep 30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("agent.sh", []byte(`echo success > "$KILL_FILE"`), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	var out strings.Builder
+	if code := runHeadless([]string{"-project", "demo", "-agent", "sh agent.sh", "-delay", "0", "-timeout", "300ms"}, &out); code != 1 {
+		t.Errorf("runHeadless() = %d, want 1\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "🧪 Ticket 1 failed verification after") || !strings.Contains(out.String(), "verification timed out after 300ms") {
+		t.Errorf("output missing the verification timeout:\n%s", out.String())
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("run took %s, the verification was not stopped", elapsed)
+	}
+}
+
+func TestSkipDuringVerification(t *testing.T) {
+	chdir(t, t.TempDir())
+	m := initialModel()
+	m.State = StateRunning
+	m.RunDir = "runs/demo/now"
+	m.Tickets = []Ticket{{Number: 1, Description: "Verifying", Status: StatusRunning, Attempts: 1, StartTime: time.Now()}}
+	agentCmd := exec.Command("true")
+	if err := agentCmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	verifyCmd := exec.Command("sleep", "30")
+	setProcessGroup(verifyCmd)
+	if err := verifyCmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- verifyCmd.Wait() }()
+	m.Running = map[int]*runningAgent{0: {Cmd: agentCmd, Verifying: true, VerifyCmd: verifyCmd}}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	m = next.(Model)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("skipping did not kill the verification command")
+	}
+	if m.Tickets[0].Status != StatusSkipped {
+		t.Errorf("Status = %q, want skipped", m.Tickets[0].Status)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// formatRetries shows a ticket's retries, which are nil when not set.
+func formatRetries(retries *int) string {
+	if retries == nil {
+		return "unset"
+	}
+	return fmt.Sprint(*retries)
+}
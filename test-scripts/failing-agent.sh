@@ -9,4 +9,5 @@ echo "[$(date +"%Y-%m-%d %H:%M:%S")] Attempting ticket $TICKET" >> agent-executi
 
 # Simulate API overload error
 echo "Repeated server overload with Opus model"
+echo "Retry-After: 5"
 exit 1
\ No newline at end of file
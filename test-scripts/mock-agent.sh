@@ -42,5 +42,5 @@ case $TICKET in
 esac
 
 # Create kill file to signal completion
-echo "success" > killmenow.md
+echo "success" > "${KILL_FILE:-killmenow.md}"
 echo "Created kill file to signal completion"
\ No newline at end of file